@@ -2,20 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"order-service/internal/cache"
 	"order-service/internal/config"
+	"order-service/internal/feed"
+	"order-service/internal/grpcserver"
 	"order-service/internal/handlers"
+	"order-service/internal/health"
 	"order-service/internal/kafka"
+	"order-service/internal/metrics"
+	"order-service/internal/outbox"
 	"order-service/internal/repository"
 	"order-service/internal/router"
 	"order-service/internal/service"
+	"order-service/internal/validator"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func main() {
@@ -24,65 +35,243 @@ func main() {
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	startDebugServer(logger)
-	
+
 	dbPool, err := initDB(cfg, logger)
 	if err != nil {
 		logger.Error("Failed to connect to database", slog.Any("error", err))
 		os.Exit(1)
 	}
-	defer dbPool.Close()
 
 	orderRepo := repository.NewPostgresRepository(dbPool)
-	orderCache := cache.NewLRUCache(cfg.Cache.CacheCapacity)
-	orderService := service.NewOrderService(orderRepo, orderCache, logger)
+	if cfg.Outbox.Topic == "" {
+		//релей не запускается: события в outbox никто не отправит и не удалит
+		orderRepo.DisableOutbox()
+	}
+	cachePolicy, err := cache.ParsePolicy(cfg.Cache.Policy)
+	if err != nil {
+		logger.Error("Invalid cache config", slog.Any("error", err))
+		os.Exit(1)
+	}
+	orderCache := cache.NewSharded(cache.Options{
+		Capacity: cfg.Cache.CacheCapacity,
+		TTL:      cfg.Cache.TTL,
+		Policy:   cachePolicy,
+		MaxBytes: cfg.Cache.MaxBytes,
+	}, cfg.Cache.Shards)
+	conflictPolicy, err := service.ParseConflictPolicy(cfg.Order.ConflictPolicy)
+	if err != nil {
+		logger.Error("Invalid order config", slog.Any("error", err))
+		os.Exit(1)
+	}
+	orderService := service.NewOrderService(orderRepo, orderCache, logger, conflictPolicy)
+	orderFeed := feed.NewHub(cfg.Feed.Buffer, logger)
+	orderService.SetFeed(orderFeed)
 
-	ctx := context.Background()
-	logger.Info("Preloading cache", slog.Int("limit", cfg.Cache.CachePreloadLimit))
-	if err = orderService.PreloadCache(ctx, cfg.Cache.CachePreloadLimit); err != nil {
-		logger.Error("Failed to preload cache", slog.Any("error", err))
+	validationMode, err := validator.ParseMode(cfg.Validation.FinancialMode)
+	if err != nil {
+		logger.Error("Invalid validation config", slog.Any("error", err))
+		os.Exit(1)
 	}
+	var validationRules *validator.RuleSet
+	if cfg.Validation.RulesFile != "" {
+		if validationRules, err = validator.LoadRules(cfg.Validation.RulesFile); err != nil {
+			logger.Error("Invalid validation rules", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+	orderValidator := validator.New(validator.Options{
+		Mode:      validationMode,
+		Tolerance: cfg.Validation.FinancialTolerance,
+		Rules:     validationRules,
+	})
 
 	kafkaProducer := kafka.NewProducer(cfg.Kafka.Brokers, cfg.Kafka.Timeout, logger)
-	defer kafkaProducer.Close()
-
-	kafkaConsumer := kafka.NewConsumer(
-		cfg.Kafka.Brokers,
-		cfg.Kafka.Topic,
-		cfg.Kafka.GroupID,
-		cfg.Kafka.MinBytes,
-		cfg.Kafka.MaxBytes,
-		cfg.Kafka.MaxWait,
-		logger,
-		orderService,
-		cfg.Kafka.DLQTopic,
-		kafkaProducer,
+
+	retryPolicy, err := kafka.NewRetryPolicy(
+		cfg.Kafka.RetryAttempts,
+		cfg.Kafka.RetryBackoff,
+		cfg.Kafka.RetryMaxBackoff,
+		cfg.Kafka.RetryTopics,
 	)
+	if err != nil {
+		logger.Error("Invalid retry policy", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	//основной топик и топики ретраев читаются отдельными консьюмерами
+	topics := append([]string{cfg.Kafka.Topic}, retryPolicy.Topics()...)
+	kafkaConsumers := make([]*kafka.Consumer, 0, len(topics))
+	for _, topic := range topics {
+		kafkaConsumers = append(kafkaConsumers, kafka.NewConsumer(
+			cfg.Kafka.Brokers,
+			topic,
+			cfg.Kafka.GroupID,
+			cfg.Kafka.MinBytes,
+			cfg.Kafka.MaxBytes,
+			cfg.Kafka.MaxWait,
+			logger,
+			orderService,
+			cfg.Kafka.DLQTopic,
+			kafkaProducer,
+			cfg.Kafka.Concurrency,
+			retryPolicy,
+			cfg.Kafka.BatchSize,
+			cfg.Kafka.BatchTimeout,
+			orderValidator,
+		))
+	}
 
-	handler := handlers.NewHandler(orderService, logger)
-	r := router.InitRouter(handler)
+	readers := make([]metrics.ReaderStatser, 0, len(kafkaConsumers))
+	for _, kafkaConsumer := range kafkaConsumers {
+		readers = append(readers, kafkaConsumer)
+	}
+	prometheus.MustRegister(
+		metrics.NewCacheCollector(orderCache),
+		metrics.NewPoolCollector(dbPool),
+		metrics.NewReaderCollector(readers...),
+	)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	//готовность: бд и брокер доступны, кеш прогрет, все консьюмеры читают свои топики
+	var cachePreloaded health.Flag
+	checker := health.NewChecker(cfg.HTTPServer.HealthTimeout)
+	checker.Add("postgres", dbPool.Ping)
+	checker.Add("kafka", func(ctx context.Context) error {
+		return kafka.PingBrokers(ctx, cfg.Kafka.Brokers)
+	})
+	checker.Add("consumer", func(context.Context) error {
+		for i, kafkaConsumer := range kafkaConsumers {
+			if !kafkaConsumer.Running() {
+				return fmt.Errorf("consumer of %s is not running", topics[i])
+			}
+		}
+		return nil
+	})
+	checker.Add("cache", cachePreloaded.Check("cache preload is not finished"))
 
-	go func() {
-		logger.Info("Starting Kafka consumer")
-		kafkaConsumer.Start(ctx)
-	}()
+	handler := handlers.NewHandler(orderService, logger, orderValidator)
+	ingestHandler := handlers.NewIngestHandler(kafkaProducer, cfg.Kafka.Topic, logger, orderValidator)
+	feedHandler := handlers.NewFeedHandler(orderFeed, logger, cfg.Feed.Heartbeat, cfg.HTTPServer.Timeout)
+	srv := newHTTPServer(cfg.HTTPServer, router.InitRouter(handler, ingestHandler, feedHandler, checker))
+	//стримы ленты не завершаются сами, без этого Shutdown ждал бы их до таймаута
+	srv.RegisterOnShutdown(orderFeed.Close)
+	grpcSrv := grpcserver.NewGRPCServer(grpcserver.NewServer(orderService, logger, orderValidator))
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go orderCache.RunJanitor(ctx, cfg.Cache.JanitorInterval)
+
+	if cfg.Validation.RulesFile != "" {
+		go orderValidator.WatchRules(ctx, cfg.Validation.RulesFile, cfg.Validation.RulesReloadInterval, logger)
+	}
+
+	//HTTP-сервер поднимается до прогрева кеша: /healthz отвечает сразу, /readyz - после прогрева
 	go func() {
 		logger.Info("Starting HTTP server", slog.String("address", cfg.HTTPServer.Address))
-		if err = r.Run(cfg.HTTPServer.Address); err != nil {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("HTTP server error", slog.Any("error", err))
-			cancel()
+			stop()
 		}
 	}()
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	if cfg.GRPCServer.Address != "" {
+		go func() {
+			lis, err := net.Listen("tcp", cfg.GRPCServer.Address)
+			if err != nil {
+				logger.Error("gRPC server listen error", slog.Any("error", err))
+				stop()
+				return
+			}
+			logger.Info("Starting gRPC server", slog.String("address", cfg.GRPCServer.Address))
+			if err = grpcSrv.Serve(lis); err != nil {
+				logger.Error("gRPC server error", slog.Any("error", err))
+				stop()
+			}
+		}()
+	}
+
+	logger.Info("Preloading cache", slog.Int("limit", cfg.Cache.CachePreloadLimit))
+	if err = orderService.PreloadCache(ctx, cfg.Cache.CachePreloadLimit); err != nil {
+		//без прогрева сервис работает, заказы дочитываются из бд
+		logger.Error("Failed to preload cache", slog.Any("error", err))
+	}
+	cachePreloaded.Set()
+
+	//консьюмеры останавливаются отдельно от сигнала: только после того, как HTTP-сервер перестал принимать запросы
+	consumersCtx, stopConsumers := context.WithCancel(context.Background())
+	defer stopConsumers()
+
+	var consumersWG sync.WaitGroup
+	for i, kafkaConsumer := range kafkaConsumers {
+		consumersWG.Add(1)
+		go func() {
+			defer consumersWG.Done()
+			logger.Info("Starting Kafka consumer", slog.String("topic", topics[i]))
+			kafkaConsumer.Start(consumersCtx)
+			if consumersCtx.Err() == nil {
+				logger.Error("Kafka consumer stopped unexpectedly", slog.String("topic", topics[i]))
+			}
+		}()
+	}
+
+	//релей outbox останавливается последним из писателей в Kafka: после него закрывается продюсер
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	relayDone := make(chan struct{})
+	if cfg.Outbox.Topic != "" {
+		relay := outbox.NewRelay(orderRepo, kafkaProducer, cfg.Outbox.Topic,
+			cfg.Outbox.PollInterval, cfg.Outbox.BatchSize, cfg.Outbox.Retention, logger)
+		go func() {
+			defer close(relayDone)
+			logger.Info("Starting outbox relay", slog.String("topic", cfg.Outbox.Topic))
+			relay.Run(relayCtx)
+		}()
+	} else {
+		close(relayDone)
+	}
+
+	<-ctx.Done()
+	logger.Info("Shutting down...", slog.Duration("timeout", cfg.ShutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	//1. перестаём принимать соединения и дожидаемся текущих HTTP- и gRPC-запросов
+	if err = srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTP server shutdown error", slog.Any("error", err))
+	}
+	stopGRPCServer(shutdownCtx, grpcSrv)
+
+	//2. консьюмеры перестают читать и коммитят сообщения, которые уже обрабатывают
+	stopConsumers()
+	consumersDone := make(chan struct{})
+	go func() {
+		consumersWG.Wait()
+		close(consumersDone)
+	}()
+	select {
+	case <-consumersDone:
+		logger.Info("Kafka consumers stopped")
+	case <-shutdownCtx.Done():
+		//незакоммиченные сообщения будут прочитаны заново после перезапуска
+		logger.Warn("Kafka consumers did not stop in time")
+	}
+
+	//3. релей останавливается; неотправленные события остаются в outbox и уйдут после перезапуска
+	stopRelay()
+	select {
+	case <-relayDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Outbox relay did not stop in time")
+	}
+
+	//4. продюсер дописывает сообщения, 5. закрываем пул соединений
+	if err = kafkaProducer.Close(); err != nil {
+		logger.Error("Kafka producer close error", slog.Any("error", err))
+	}
+	dbPool.Close()
 
-	logger.Info("Shutting down...")
-	cancel()
+	logger.Info("Service stopped")
 }
 
 func initDB(cfg *config.Config, logger *slog.Logger) (*pgxpool.Pool, error) {
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"order-service/internal/config"
+
+	"google.golang.org/grpc"
+)
+
+// newHTTPServer - HTTP-сервер с таймаутами из конфига.
+// Timeout ограничивает чтение запроса и запись ответа, IdleTimeout - простой keep-alive соединения
+func newHTTPServer(cfg config.HTTPServer, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Address,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Timeout,
+		ReadTimeout:       cfg.Timeout,
+		WriteTimeout:      cfg.Timeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// stopGRPCServer - дожидается текущих вызовов и стримов, по истечении ctx обрывает их
+func stopGRPCServer(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"order-service/internal/kafka"
+	"order-service/internal/models"
+	"order-service/internal/validator"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Утилита для разбора DLQ: просмотр сообщений, сгруппированных по причине ошибки,
+// просмотр одного сообщения с заголовками и повторная отправка в исходный топик.
+//
+//	go run ./cmd/dlq list -reason validation_failed
+//	go run ./cmd/dlq show -partition 0 -offset 12
+//	go run ./cmd/dlq replay -reason retries_exhausted -validate -dry-run
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	_ = godotenv.Load()
+
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	brokers := fs.String("brokers", envOrDefault("KAFKA_BROKERS", "localhost:9092"), "comma-separated list of brokers")
+	topic := fs.String("topic", envOrDefault("KAFKA_DLQ_TOPIC", "orders_dlq"), "DLQ topic")
+	reason := fs.String("reason", "", "filter by error_reason header")
+	key := fs.String("key", "", "filter by message key")
+	since := fs.String("since", "", "filter messages produced at or after this time (RFC3339)")
+	until := fs.String("until", "", "filter messages produced before this time (RFC3339)")
+	partition := fs.Int("partition", -1, "filter by DLQ partition")
+	offset := fs.Int64("offset", -1, "filter by DLQ offset")
+	validate := fs.Bool("validate", false, "replay: skip messages that fail validation (orders and status events)")
+	dryRun := fs.Bool("dry-run", false, "replay: only print what would be sent")
+	_ = fs.Parse(os.Args[2:])
+
+	f, err := newFilter(*reason, *key, *since, *until, *partition, *offset)
+	if err != nil {
+		log.Fatalf("invalid filter: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	brokerList := strings.Split(*brokers, ",")
+	msgs, err := readTopic(ctx, brokerList, *topic)
+	if err != nil {
+		log.Fatalf("read %s: %v", *topic, err)
+	}
+
+	selected := make([]kafkago.Message, 0, len(msgs))
+	for _, m := range msgs {
+		if f.match(m) {
+			selected = append(selected, m)
+		}
+	}
+
+	switch cmd {
+	case "list":
+		list(selected)
+	case "show":
+		if *offset < 0 {
+			log.Fatal("show requires -offset")
+		}
+		show(selected)
+	case "replay":
+		var v *validator.Validator
+		if *validate {
+			if v, err = newValidator(); err != nil {
+				log.Fatalf("invalid validation config: %v", err)
+			}
+		}
+		replay(ctx, brokerList, selected, v, *dryRun)
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq <list|show|replay> [flags]")
+	fmt.Fprintln(os.Stderr, "run 'dlq <command> -h' for the list of flags")
+}
+
+type filter struct {
+	reason    string
+	key       string
+	since     time.Time
+	until     time.Time
+	partition int
+	offset    int64
+}
+
+func newFilter(reason, key, since, until string, partition int, offset int64) (*filter, error) {
+	f := &filter{reason: reason, key: key, partition: partition, offset: offset}
+
+	var err error
+	if since != "" {
+		if f.since, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, fmt.Errorf("since: %w", err)
+		}
+	}
+	if until != "" {
+		if f.until, err = time.Parse(time.RFC3339, until); err != nil {
+			return nil, fmt.Errorf("until: %w", err)
+		}
+	}
+
+	return f, nil
+}
+
+func (f *filter) match(m kafkago.Message) bool {
+	if f.reason != "" && header(m, kafka.HeaderErrorReason) != f.reason {
+		return false
+	}
+	if f.key != "" && string(m.Key) != f.key {
+		return false
+	}
+	if !f.since.IsZero() && m.Time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !m.Time.Before(f.until) {
+		return false
+	}
+	if f.partition >= 0 && m.Partition != f.partition {
+		return false
+	}
+	if f.offset >= 0 && m.Offset != f.offset {
+		return false
+	}
+	return true
+}
+
+// readTopic - читает все сообщения топика, которые есть в нём на момент запуска.
+// Читаем партиции напрямую, без consumer group, чтобы не сдвигать ничьих offset'ов
+func readTopic(ctx context.Context, brokers []string, topic string) ([]kafkago.Message, error) {
+	conn, err := kafkago.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read partitions: %w", err)
+	}
+
+	var msgs []kafkago.Message
+	for _, p := range partitions {
+		leader, err := kafkago.DialLeader(ctx, "tcp", brokers[0], topic, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("dial leader of partition %d: %w", p.ID, err)
+		}
+		first, last, err := leader.ReadOffsets()
+		leader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read offsets of partition %d: %w", p.ID, err)
+		}
+		if first >= last {
+			continue
+		}
+
+		r := kafkago.NewReader(kafkago.ReaderConfig{
+			Brokers:   brokers,
+			Topic:     topic,
+			Partition: p.ID,
+			MaxBytes:  10e6,
+		})
+		if err = r.SetOffset(first); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("set offset of partition %d: %w", p.ID, err)
+		}
+
+		for {
+			m, err := r.ReadMessage(ctx)
+			if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("read partition %d: %w", p.ID, err)
+			}
+			msgs = append(msgs, m)
+			if m.Offset >= last-1 {
+				break
+			}
+		}
+		r.Close()
+	}
+
+	return msgs, nil
+}
+
+func list(msgs []kafkago.Message) {
+	byReason := make(map[string][]kafkago.Message)
+	for _, m := range msgs {
+		reason := header(m, kafka.HeaderErrorReason)
+		if reason == "" {
+			reason = "unknown"
+		}
+		byReason[reason] = append(byReason[reason], m)
+	}
+
+	reasons := make([]string, 0, len(byReason))
+	for r := range byReason {
+		reasons = append(reasons, r)
+	}
+	sort.Strings(reasons)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "\n== %s (%d) ==\n", reason, len(byReason[reason]))
+		fmt.Fprintln(w, "PARTITION\tOFFSET\tTIME\tKEY\tORIGIN\tDETAILS")
+		for _, m := range byReason[reason] {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s:%s\t%s\n",
+				m.Partition, m.Offset, m.Time.Format(time.RFC3339), m.Key,
+				header(m, kafka.HeaderOriginalTopic), header(m, kafka.HeaderOriginalOffset),
+				truncate(header(m, kafka.HeaderErrorDetails), 80),
+			)
+		}
+	}
+	w.Flush()
+
+	listValidationErrors(msgs)
+
+	fmt.Printf("\nTotal: %d\n", len(msgs))
+}
+
+// listValidationErrors - сводка ошибок валидации по полям и кодам из заголовка validation_errors
+func listValidationErrors(msgs []kafkago.Message) {
+	counts := make(map[validator.FieldError]int)
+	for _, m := range msgs {
+		raw := header(m, kafka.HeaderValidationErrors)
+		if raw == "" {
+			continue
+		}
+		var verrs validator.ValidationErrors
+		if err := json.Unmarshal([]byte(raw), &verrs); err != nil {
+			continue
+		}
+		for _, fe := range verrs {
+			counts[validator.FieldError{Field: fe.Field, Code: fe.Code}]++
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]validator.FieldError, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i].String() < keys[j].String()
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\n== validation errors ==")
+	fmt.Fprintln(w, "FIELD\tCODE\tCOUNT")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", k.Field, k.Code, counts[k])
+	}
+	w.Flush()
+}
+
+func show(msgs []kafkago.Message) {
+	if len(msgs) == 0 {
+		fmt.Println("No message found")
+		return
+	}
+
+	for _, m := range msgs {
+		fmt.Printf("Partition: %d\nOffset:    %d\nTime:      %s\nKey:       %s\n",
+			m.Partition, m.Offset, m.Time.Format(time.RFC3339), m.Key)
+		fmt.Println("Headers:")
+		for _, h := range m.Headers {
+			fmt.Printf("  %s: %s\n", h.Key, h.Value)
+		}
+		fmt.Println("Value:")
+		fmt.Println(string(m.Value))
+		fmt.Println()
+	}
+}
+
+func replay(ctx context.Context, brokers []string, msgs []kafkago.Message, v *validator.Validator, dryRun bool) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	producer := kafka.NewProducer(brokers, 10*time.Second, logger)
+	defer producer.Close()
+
+	var sent, skipped, failed int
+	for _, m := range msgs {
+		target := header(m, kafka.HeaderOriginalTopic)
+		if target == "" {
+			log.Printf("skip %d:%d: no %s header", m.Partition, m.Offset, kafka.HeaderOriginalTopic)
+			skipped++
+			continue
+		}
+
+		if v != nil {
+			if err := validateMessage(v, m); err != nil {
+				log.Printf("skip %d:%d: %v", m.Partition, m.Offset, err)
+				skipped++
+				continue
+			}
+		}
+
+		if dryRun {
+			fmt.Printf("would replay %d:%d key=%s to %s\n", m.Partition, m.Offset, m.Key, target)
+			sent++
+			continue
+		}
+
+		if err := producer.SendMessage(ctx, target, m.Key, m.Value, replayHeaders(m)); err != nil {
+			log.Printf("replay %d:%d: %v", m.Partition, m.Offset, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	action := "Replayed"
+	if dryRun {
+		action = "Would replay"
+	}
+	fmt.Printf("%s: %d, skipped: %d, failed: %d\n", action, sent, skipped, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// newValidator - валидатор с теми же настройками финансовых проверок, что и у сервиса
+func newValidator() (*validator.Validator, error) {
+	mode, err := validator.ParseMode(envOrDefault("VALIDATION_FINANCIAL_MODE", string(validator.ModeStrict)))
+	if err != nil {
+		return nil, err
+	}
+	tolerance, err := strconv.Atoi(envOrDefault("VALIDATION_FINANCIAL_TOLERANCE", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("VALIDATION_FINANCIAL_TOLERANCE: %w", err)
+	}
+	opts := validator.Options{Mode: mode, Tolerance: tolerance}
+	if path := os.Getenv("VALIDATION_RULES_FILE"); path != "" {
+		if opts.Rules, err = validator.LoadRules(path); err != nil {
+			return nil, err
+		}
+	}
+	return validator.New(opts), nil
+}
+
+// replayHeaders - заголовки повторной отправки. Ошибка и счётчик ретраев не переносятся: сообщение
+// проходит пайплайн заново. Тип события сохраняется, иначе событие статуса разберётся как новый заказ
+func replayHeaders(m kafkago.Message) map[string]string {
+	headers := map[string]string{
+		"replayed_from": fmt.Sprintf("%s:%d:%d", m.Topic, m.Partition, m.Offset),
+	}
+	if eventType := header(m, kafka.HeaderEventType); eventType != "" {
+		headers[kafka.HeaderEventType] = eventType
+	}
+	return headers
+}
+
+// validateMessage - проверяет сообщение так же, как консьюмер: событие статуса - как событие, остальное - как заказ
+func validateMessage(v *validator.Validator, m kafkago.Message) error {
+	if header(m, kafka.HeaderEventType) == kafka.EventOrderStatus {
+		var event models.StatusEvent
+		if err := json.Unmarshal(m.Value, &event); err != nil {
+			return fmt.Errorf("invalid json: %w", err)
+		}
+		return validator.ValidateStatusEvent(&event)
+	}
+
+	var order models.Order
+	if err := json.Unmarshal(m.Value, &order); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	return v.Validate(nil, &order)
+}
+
+func header(m kafkago.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
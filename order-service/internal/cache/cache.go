@@ -2,56 +2,214 @@ package cache
 
 import (
 	"container/list"
+	"context"
 	"order-service/internal/models"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Clock - источник времени для сроков жизни записей, в тестах подменяется управляемыми часами
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Options - настройки кеша
+type Options struct {
+	Capacity int
+	// срок жизни записи по умолчанию; 0 - записи не устаревают и вытесняются только по capacity
+	TTL time.Duration
+	// nil - системные часы
+	Clock Clock
+	// алгоритм вытеснения для NewSharded; New всегда создаёт LRU
+	Policy Policy
+	// бюджет памяти по оценке EstimateSize; 0 - только ограничение по Capacity.
+	// Capacity ограничивает число записей и при заданном бюджете
+	MaxBytes int64
+
+	//общий счётчик байт шардов ShardedCache: бюджет MaxBytes действует на все шарды вместе
+	sharedBytes *atomic.Int64
+}
+
 type LRUCache struct {
 	mu       sync.Mutex
 	capacity int
-	cache    map[string]*list.Element
-	lru      *list.List
+	maxBytes int64
+	//оценка памяти всех записей
+	bytes int64
+	//nil - кеш сам по себе, иначе бюджет общий с другими шардами
+	shared *atomic.Int64
+	ttl    time.Duration
+	clock  Clock
+	cache  map[string]*list.Element
+	lru    *list.List
+	//содержит только заказы, лежащие в кеше
+	tracks trackIndex
+
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+}
+
+// Stats - счётчики кеша с момента создания
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// сколько записей удалено по истечении срока жизни
+	Expirations uint64
+	Size        int
+	Capacity    int
+	// оценка занятой памяти и бюджет (0 - без бюджета)
+	Bytes    int64
+	MaxBytes int64
 }
 
 type cacheItem struct {
 	key   string
 	order *models.Order
+	//нулевое значение - запись не устаревает
+	expiresAt time.Time
+	size      int64
 }
 
 func NewLRUCache(capacity int) *LRUCache {
+	return New(Options{Capacity: capacity})
+}
+
+// New - LRU-кеш с необязательными сроком жизни записей и бюджетом памяти
+func New(opts Options) *LRUCache {
+	clock := opts.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
 	return &LRUCache{
-		capacity: capacity,
+		capacity: opts.Capacity,
+		maxBytes: opts.MaxBytes,
+		shared:   opts.sharedBytes,
+		ttl:      opts.TTL,
+		clock:    clock,
 		cache:    make(map[string]*list.Element),
 		lru:      list.New(),
+		tracks:   make(trackIndex),
 	}
 }
 
-// Set - устанавливает заказ в LRUCache
+// Set - устанавливает заказ в LRUCache со сроком жизни по умолчанию
 func (c *LRUCache) Set(order *models.Order) {
+	c.SetWithTTL(order, c.ttl)
+}
+
+// SetWithTTL - устанавливает заказ в LRUCache со своим сроком жизни; ttl <= 0 - запись не устаревает.
+// Заказ больше всего бюджета памяти не кешируется
+func (c *LRUCache) SetWithTTL(order *models.Order, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	expiresAt := deadline(c.clock, ttl)
+	size := EstimateSize(order)
+	tooLarge := c.maxBytes > 0 && size > c.maxBytes
+
 	//если элемент есть - переносим в голову и обновляем значение
 	if elem, exists := c.cache[order.OrderUID]; exists {
+		if tooLarge {
+			//старая версия не должна остаться в кеше
+			c.evict(elem)
+			return
+		}
 		c.lru.MoveToFront(elem)
-		elem.Value.(*cacheItem).order = order
+		item := elem.Value.(*cacheItem)
+		c.tracks.remove(item.order)
+		item.order = order
+		item.expiresAt = expiresAt
+		c.addBytes(size - item.size)
+		item.size = size
+		c.tracks.add(order)
+		c.evictOverBudget()
+		return
+	}
+
+	if tooLarge {
 		return
 	}
 
 	//если достигли лимита - удаляем самый старый элемент из списка и из мапы
 	if c.capacity <= c.lru.Len() {
-		lastItem := c.lru.Back()
-		c.lru.Remove(lastItem)
-		delete(c.cache, lastItem.Value.(*cacheItem).key)
+		c.evict(c.lru.Back())
 	}
 
 	//добавляем новый элемент в начало списка и в мапу
 	newItem := &cacheItem{
-		key:   order.OrderUID,
-		order: order,
+		key:       order.OrderUID,
+		order:     order,
+		expiresAt: expiresAt,
+		size:      size,
 	}
 	elem := c.lru.PushFront(newItem)
 	c.cache[order.OrderUID] = elem
+	c.tracks.add(order)
+	c.addBytes(size)
+	c.evictOverBudget()
+}
+
+// evictOverBudget - вытесняет самые старые записи, пока оценка памяти больше бюджета.
+// Только что записанный заказ стоит в голове и не больше бюджета, поэтому он остаётся.
+// При общем бюджете остальное освобождает ShardedCache из других шардов
+func (c *LRUCache) evictOverBudget() {
+	for c.overBudget() && c.lru.Len() > 1 {
+		c.evict(c.lru.Back())
+	}
+}
+
+// evictOne - вытесняет самую старую запись, false - кеш пуст
+func (c *LRUCache) evictOne() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lru.Len() == 0 {
+		return false
+	}
+	c.evict(c.lru.Back())
+	return true
+}
+
+// addBytes - меняет оценку памяти кеша и общего бюджета шардов
+func (c *LRUCache) addBytes(delta int64) {
+	c.bytes += delta
+	if c.shared != nil {
+		c.shared.Add(delta)
+	}
+}
+
+// usedBytes - занятая память, с которой сравнивается бюджет: своя или общая для всех шардов
+func (c *LRUCache) usedBytes() int64 {
+	if c.shared != nil {
+		return c.shared.Load()
+	}
+	return c.bytes
+}
+
+func (c *LRUCache) overBudget() bool {
+	return c.maxBytes > 0 && c.usedBytes() > c.maxBytes
+}
+
+// evict - удаляет запись из списка, мапы и индекса по track_number
+func (c *LRUCache) evict(elem *list.Element) {
+	c.remove(elem)
+	c.evictions++
+}
+
+func (c *LRUCache) remove(elem *list.Element) {
+	item := elem.Value.(*cacheItem)
+	c.lru.Remove(elem)
+	delete(c.cache, item.key)
+	c.tracks.remove(item.order)
+	c.addBytes(-item.size)
 }
 
 // Get - получает заказ из LRUCache по orderUID
@@ -61,13 +219,159 @@ func (c *LRUCache) Get(orderUID string) (*models.Order, bool) {
 
 	elem, exists := c.cache[orderUID]
 	if !exists {
+		c.misses++
 		return nil, false
 	}
 
+	//устаревшая запись удаляется при обращении, не дожидаясь очистки
+	if expired(elem.Value.(*cacheItem).expiresAt, c.clock.Now()) {
+		c.removeExpired(elem)
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
 	c.lru.MoveToFront(elem)
 	return elem.Value.(*cacheItem).order, true
 }
 
+// GetByTrack - получает заказ из LRUCache по track_number через вторичный индекс
+func (c *LRUCache) GetByTrack(trackNumber string) (*models.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	orderUID, exists := c.tracks[trackNumber]
+	if !exists {
+		c.misses++
+		return nil, false
+	}
+
+	elem := c.cache[orderUID]
+	if expired(elem.Value.(*cacheItem).expiresAt, c.clock.Now()) {
+		c.removeExpired(elem)
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cacheItem).order, true
+}
+
+// peekTrack - заказ по track_number без учёта в счётчиках и без изменения LRU-порядка
+func (c *LRUCache) peekTrack(trackNumber string) (*models.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	orderUID, exists := c.tracks[trackNumber]
+	if !exists {
+		return nil, false
+	}
+
+	item := c.cache[orderUID].Value.(*cacheItem)
+	if expired(item.expiresAt, c.clock.Now()) {
+		return nil, false
+	}
+	return item.order, true
+}
+
+// Stats - возвращает снимок счётчиков кеша
+func (c *LRUCache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+		Size:        c.lru.Len(),
+		Capacity:    c.capacity,
+		Bytes:       c.bytes,
+		MaxBytes:    c.maxBytes,
+	}
+}
+
+// DeleteExpired - удаляет все устаревшие записи и возвращает их число
+func (c *LRUCache) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	deleted := 0
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if expired(elem.Value.(*cacheItem).expiresAt, now) {
+			c.removeExpired(elem)
+			deleted++
+		}
+		elem = prev
+	}
+	return deleted
+}
+
+// RunJanitor - раз в interval удаляет устаревшие записи, пока не отменён ctx.
+// Без неё устаревшие записи, к которым больше не обращаются, занимали бы место до вытеснения
+func (c *LRUCache) RunJanitor(ctx context.Context, interval time.Duration) {
+	runJanitor(ctx, c.ttl, interval, c.DeleteExpired)
+}
+
+// runJanitor - вызывает sweep раз в interval (по умолчанию раз в ttl), пока не отменён ctx
+func runJanitor(ctx context.Context, ttl, interval time.Duration, sweep func() int) {
+	if ttl <= 0 && interval <= 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = ttl
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweep()
+		}
+	}
+}
+
+func (c *LRUCache) removeExpired(elem *list.Element) {
+	c.remove(elem)
+	c.expirations++
+}
+
+// trackIndex - вторичный индекс track_number -> order_uid
+type trackIndex map[string]string
+
+// add - добавляет заказ в индекс
+func (t trackIndex) add(order *models.Order) {
+	if order.TrackNumber != "" {
+		t[order.TrackNumber] = order.OrderUID
+	}
+}
+
+// remove - убирает заказ из индекса, если трек-номер ещё указывает на него
+func (t trackIndex) remove(order *models.Order) {
+	if t[order.TrackNumber] == order.OrderUID {
+		delete(t, order.TrackNumber)
+	}
+}
+
+// deadline - момент устаревания записи, записанной сейчас; ttl <= 0 - нулевое время, запись не устаревает
+func deadline(clock Clock, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return clock.Now().Add(ttl)
+}
+
+func expired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
+
 // LoadBatch - метод LRUCache, позволяющий предзагрузить данные на старте
 func (c *LRUCache) LoadBatch(orders []*models.Order) {
 	c.mu.Lock()
@@ -76,18 +380,29 @@ func (c *LRUCache) LoadBatch(orders []*models.Order) {
 	//очищаем на всякий случай старый кеш
 	c.cache = make(map[string]*list.Element, len(orders))
 	c.lru = list.New()
+	c.tracks = make(trackIndex, len(orders))
+	c.addBytes(-c.bytes)
 
-	// Загружаем новые данные (до capacity)
+	// Загружаем новые данные (до capacity и бюджета памяти)
+	expiresAt := deadline(c.clock, c.ttl)
 	for _, order := range orders {
 		if c.lru.Len() >= c.capacity {
 			break
 		}
+		size := EstimateSize(order)
+		if c.maxBytes > 0 && c.usedBytes()+size > c.maxBytes {
+			break
+		}
 		item := &cacheItem{
-			key:   order.OrderUID,
-			order: order,
+			key:       order.OrderUID,
+			order:     order,
+			expiresAt: expiresAt,
+			size:      size,
 		}
 		elem := c.lru.PushFront(item)
 		c.cache[order.OrderUID] = elem
+		c.tracks.add(order)
+		c.addBytes(size)
 	}
 
 }
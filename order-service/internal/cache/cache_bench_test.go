@@ -2,7 +2,9 @@ package cache
 
 import (
 	"fmt"
+	"math/rand"
 	"order-service/internal/models"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -121,3 +123,176 @@ func BenchmarkLRUCache_Get_Parallel(b *testing.B) {
 		}
 	})
 }
+
+// Конкурентный доступ: один LRUCache с общим мьютексом против ShardedCache.
+// Разница видна при нескольких ядрах:
+// go test -run '^$' -bench Contention -benchmem -cpu 1,4,8,16 ./internal/cache/
+
+type benchCache interface {
+	Get(string) (*models.Order, bool)
+	Set(*models.Order)
+}
+
+const contentionKeys = 1000
+
+func contentionCaches() map[string]func() benchCache {
+	return map[string]func() benchCache{
+		"LRU":       func() benchCache { return NewLRUCache(contentionKeys) },
+		"Sharded16": func() benchCache { return NewSharded(Options{Capacity: contentionKeys * 2}, 16) },
+		"Sharded64": func() benchCache { return NewSharded(Options{Capacity: contentionKeys * 2}, 64) },
+	}
+}
+
+// benchmarkContention - параллельные чтения и writePercent% записей по contentionKeys заказам
+func benchmarkContention(b *testing.B, newCache func() benchCache, writePercent int) {
+	c := newCache()
+	orders := make([]*models.Order, contentionKeys)
+	for i := range orders {
+		orders[i] = newTestOrder(fmt.Sprintf("order-%d", i))
+		c.Set(orders[i])
+	}
+
+	var worker atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		//каждая горутина начинает со своего места, чтобы не читать одни и те же ключи синхронно
+		i := int(worker.Add(1)) * 7919
+		for pb.Next() {
+			order := orders[(i*31)%contentionKeys]
+			if i%100 < writePercent {
+				c.Set(order)
+			} else {
+				c.Get(order.OrderUID)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkContention_Get(b *testing.B) {
+	for _, name := range []string{"LRU", "Sharded16", "Sharded64"} {
+		b.Run(name, func(b *testing.B) {
+			benchmarkContention(b, contentionCaches()[name], 0)
+		})
+	}
+}
+
+func BenchmarkContention_Mixed(b *testing.B) {
+	for _, name := range []string{"LRU", "Sharded16", "Sharded64"} {
+		b.Run(name, func(b *testing.B) {
+			benchmarkContention(b, contentionCaches()[name], 10)
+		})
+	}
+}
+
+func BenchmarkContention_GetByTrack(b *testing.B) {
+	for _, name := range []string{"LRU", "Sharded16"} {
+		b.Run(name, func(b *testing.B) {
+			c := contentionCaches()[name]().(interface {
+				benchCache
+				GetByTrack(string) (*models.Order, bool)
+			})
+			tracks := make([]string, contentionKeys)
+			for i := range tracks {
+				order := newTestOrder(fmt.Sprintf("order-%d", i))
+				order.TrackNumber = fmt.Sprintf("TRACK-%d", i)
+				tracks[i] = order.TrackNumber
+				c.Set(order)
+			}
+
+			var worker atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(worker.Add(1)) * 7919
+				for pb.Next() {
+					c.GetByTrack(tracks[(i*31)%contentionKeys])
+					i++
+				}
+			})
+		})
+	}
+}
+
+// Доля попаданий разных алгоритмов вытеснения на Zipf-потоке: немногие заказы запрашивают часто, остальные редко.
+// В WithScans поток перемежается всплесками разовых обращений, которые вымывают горячие заказы из LRU.
+// Одна итерация - прогон всего потока на пустом кеше, результат - метрика hit%:
+// go test -run '^$' -bench HitRatio ./internal/cache/
+
+const (
+	hitRatioKeys     = 100_000
+	hitRatioCapacity = 1_000
+	hitRatioTrace    = 200_000
+)
+
+var hitRatioPolicies = []struct {
+	name     string
+	newCache func() benchCache
+}{
+	{"LRU", func() benchCache { return NewLRUCache(hitRatioCapacity) }},
+	{"LFU", func() benchCache { return NewLFU(Options{Capacity: hitRatioCapacity}) }},
+	{"TinyLFU", func() benchCache { return NewTinyLFU(Options{Capacity: hitRatioCapacity}) }},
+}
+
+// zipfTrace - поток заказов по Zipf; scanEvery > 0 - каждые scanEvery обращений вставляется scanLen разовых
+func zipfTrace(scanEvery, scanLen int) []*models.Order {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.07, 1, hitRatioKeys-1)
+
+	orders := make(map[string]*models.Order)
+	order := func(uid string) *models.Order {
+		o, ok := orders[uid]
+		if !ok {
+			o = &models.Order{OrderUID: uid}
+			orders[uid] = o
+		}
+		return o
+	}
+
+	trace := make([]*models.Order, 0, hitRatioTrace)
+	scanned := 0
+	for len(trace) < hitRatioTrace {
+		if scanEvery > 0 && len(trace) > 0 && len(trace)%scanEvery == 0 {
+			for i := 0; i < scanLen; i++ {
+				trace = append(trace, order(fmt.Sprintf("scan-%d", scanned)))
+				scanned++
+			}
+		}
+		trace = append(trace, order(fmt.Sprintf("order-%d", zipf.Uint64())))
+	}
+	return trace
+}
+
+// benchmarkHitRatio - как сервис: при промахе заказ читается из бд и кладётся в кеш
+func benchmarkHitRatio(b *testing.B, newCache func() benchCache, trace []*models.Order) {
+	var hits int
+	for i := 0; i < b.N; i++ {
+		c := newCache()
+		hits = 0
+		for _, order := range trace {
+			if _, ok := c.Get(order.OrderUID); ok {
+				hits++
+			} else {
+				c.Set(order)
+			}
+		}
+	}
+	b.ReportMetric(100*float64(hits)/float64(len(trace)), "hit%")
+}
+
+func BenchmarkHitRatio_Zipf(b *testing.B) {
+	trace := zipfTrace(0, 0)
+	for _, p := range hitRatioPolicies {
+		b.Run(p.name, func(b *testing.B) {
+			benchmarkHitRatio(b, p.newCache, trace)
+		})
+	}
+}
+
+func BenchmarkHitRatio_ZipfWithScans(b *testing.B) {
+	trace := zipfTrace(10_000, 2*hitRatioCapacity)
+	for _, p := range hitRatioPolicies {
+		b.Run(p.name, func(b *testing.B) {
+			benchmarkHitRatio(b, p.newCache, trace)
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"order-service/internal/models"
 )
@@ -134,6 +136,88 @@ func TestLoadBatchReplaceOldData(t *testing.T) {
 	}
 }
 
+func TestStats(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set(makeOrder("1"))
+	c.Set(makeOrder("2"))
+	c.Set(makeOrder("3")) // вытесняет "1"
+	c.Get("1")            // промах
+	c.Get("2")            // попадание
+
+	want := Stats{Hits: 1, Misses: 1, Evictions: 1, Size: 2, Capacity: 2,
+		Bytes: EstimateSize(makeOrder("2")) + EstimateSize(makeOrder("3"))}
+	if got := c.Stats(); got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+// Индекс по track_number
+
+func makeTrackedOrder(uid, track string) *models.Order {
+	o := makeOrder(uid)
+	o.TrackNumber = track
+	return o
+}
+
+func TestGetByTrack(t *testing.T) {
+	c := NewLRUCache(2)
+
+	o1 := makeTrackedOrder("1", "TRK-1")
+	c.Set(o1)
+
+	got, ok := c.GetByTrack("TRK-1")
+	if !ok || got != o1 {
+		t.Fatal("cannot retrieve order by track number")
+	}
+	if _, ok := c.GetByTrack("TRK-2"); ok {
+		t.Fatal("unknown track number must miss")
+	}
+}
+
+func TestGetByTrackMovesToFront(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set(makeTrackedOrder("1", "TRK-1"))
+	c.Set(makeTrackedOrder("2", "TRK-2"))
+	c.GetByTrack("TRK-1")                 // "1" теперь свежий
+	c.Set(makeTrackedOrder("3", "TRK-3")) // должен вытеснить "2"
+
+	if _, ok := c.GetByTrack("TRK-2"); ok {
+		t.Fatal("evicted order must be removed from track index")
+	}
+	if _, ok := c.GetByTrack("TRK-1"); !ok {
+		t.Fatal("order 1 must still be indexed")
+	}
+}
+
+func TestGetByTrackAfterUpdate(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set(makeTrackedOrder("1", "TRK-OLD"))
+	c.Set(makeTrackedOrder("1", "TRK-NEW"))
+
+	if _, ok := c.GetByTrack("TRK-OLD"); ok {
+		t.Fatal("old track number must be removed from index")
+	}
+	if got, ok := c.GetByTrack("TRK-NEW"); !ok || got.OrderUID != "1" {
+		t.Fatal("new track number must point to the order")
+	}
+}
+
+func TestGetByTrackLoadBatch(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set(makeTrackedOrder("OLD", "TRK-OLD"))
+	c.LoadBatch([]*models.Order{makeTrackedOrder("NEW", "TRK-NEW")})
+
+	if _, ok := c.GetByTrack("TRK-OLD"); ok {
+		t.Fatal("index must be rebuilt on LoadBatch")
+	}
+	if _, ok := c.GetByTrack("TRK-NEW"); !ok {
+		t.Fatal("loaded order must be indexed")
+	}
+}
+
 // Конкурентный доступ
 func TestConcurrentAccess(t *testing.T) {
 	c := NewLRUCache(100)
@@ -154,3 +238,206 @@ func TestConcurrentAccess(t *testing.T) {
 		t.Fatalf("expected len %d, got %d", c.capacity, c.lru.Len())
 	}
 }
+
+// Срок жизни записей
+
+// fakeClock - часы, которые двигаются только вручную
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTTLExpiresOnGet(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 2, TTL: time.Minute, Clock: clock})
+
+	c.Set(makeTrackedOrder("1", "TRK-1"))
+
+	clock.Advance(59 * time.Second)
+	if _, ok := c.Get("1"); !ok {
+		t.Fatal("order must be alive before TTL")
+	}
+
+	clock.Advance(time.Second)
+	if _, ok := c.Get("1"); ok {
+		t.Fatal("order must expire after TTL")
+	}
+	if _, ok := c.GetByTrack("TRK-1"); ok {
+		t.Fatal("expired order must be removed from track index")
+	}
+
+	want := Stats{Hits: 1, Misses: 2, Expirations: 1, Size: 0, Capacity: 2}
+	if got := c.Stats(); got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestTTLExpiresOnGetByTrack(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 2, TTL: time.Minute, Clock: clock})
+
+	c.Set(makeTrackedOrder("1", "TRK-1"))
+	clock.Advance(time.Minute)
+
+	if _, ok := c.GetByTrack("TRK-1"); ok {
+		t.Fatal("order must expire after TTL")
+	}
+	if c.lru.Len() != 0 || len(c.cache) != 0 {
+		t.Fatal("expired order must be removed")
+	}
+}
+
+func TestSetRefreshesTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 2, TTL: time.Minute, Clock: clock})
+
+	c.Set(makeOrder("1"))
+	clock.Advance(50 * time.Second)
+	c.Set(makeOrder("1")) // новая версия живёт полный TTL
+	clock.Advance(50 * time.Second)
+
+	if _, ok := c.Get("1"); !ok {
+		t.Fatal("updated order must get a fresh TTL")
+	}
+}
+
+func TestGetDoesNotRefreshTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 2, TTL: time.Minute, Clock: clock})
+
+	c.Set(makeOrder("1"))
+	clock.Advance(50 * time.Second)
+	c.Get("1")
+	clock.Advance(10 * time.Second)
+
+	if _, ok := c.Get("1"); ok {
+		t.Fatal("reading must not extend TTL")
+	}
+}
+
+func TestSetWithTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 3, TTL: time.Minute, Clock: clock})
+
+	c.SetWithTTL(makeOrder("short"), 10*time.Second)
+	c.SetWithTTL(makeOrder("forever"), 0)
+	c.Set(makeOrder("default"))
+
+	clock.Advance(10 * time.Second)
+	if _, ok := c.Get("short"); ok {
+		t.Fatal("per-entry TTL must override the default")
+	}
+	if _, ok := c.Get("default"); !ok {
+		t.Fatal("default TTL must still hold")
+	}
+
+	clock.Advance(time.Hour)
+	if _, ok := c.Get("default"); ok {
+		t.Fatal("default TTL must expire")
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Fatal("entry without TTL must not expire")
+	}
+}
+
+func TestNoTTLByDefault(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 2, Clock: clock})
+
+	c.Set(makeOrder("1"))
+	clock.Advance(24 * 365 * time.Hour)
+
+	if _, ok := c.Get("1"); !ok {
+		t.Fatal("cache without TTL must not expire entries")
+	}
+}
+
+func TestLoadBatchTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 2, TTL: time.Minute, Clock: clock})
+
+	c.LoadBatch([]*models.Order{makeOrder("A"), makeOrder("B")})
+	clock.Advance(time.Minute)
+
+	if _, ok := c.Get("A"); ok {
+		t.Fatal("preloaded orders must expire")
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 4, TTL: time.Minute, Clock: clock})
+
+	c.Set(makeTrackedOrder("1", "TRK-1"))
+	c.Set(makeTrackedOrder("2", "TRK-2"))
+	clock.Advance(30 * time.Second)
+	c.Set(makeTrackedOrder("3", "TRK-3"))
+	c.SetWithTTL(makeTrackedOrder("4", "TRK-4"), 0)
+
+	clock.Advance(30 * time.Second)
+	if n := c.DeleteExpired(); n != 2 {
+		t.Fatalf("want 2 expired, got %d", n)
+	}
+	if c.lru.Len() != 2 || len(c.cache) != 2 || len(c.tracks) != 2 {
+		t.Fatal("expired orders must be removed from list, map and track index")
+	}
+	if _, ok := c.GetByTrack("TRK-3"); !ok {
+		t.Fatal("order 3 must still be alive")
+	}
+	if got := c.Stats().Expirations; got != 2 {
+		t.Fatalf("want 2 expirations, got %d", got)
+	}
+
+	clock.Advance(time.Hour)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Fatalf("want 1 expired, got %d", n)
+	}
+	if _, ok := c.Get("4"); !ok {
+		t.Fatal("entry without TTL must not be swept")
+	}
+}
+
+func TestRunJanitor(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Options{Capacity: 2, TTL: time.Minute, Clock: clock})
+	c.Set(makeOrder("1"))
+	clock.Advance(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.RunJanitor(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().Size != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor did not sweep expired entries")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("janitor did not stop")
+	}
+}
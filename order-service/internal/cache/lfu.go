@@ -0,0 +1,90 @@
+package cache
+
+import "container/list"
+
+// lfu - вытеснение по числу обращений за O(1): записи лежат в списках по частоте,
+// внутри списка - от недавних к давним, поэтому при равной частоте вытесняется давний заказ
+type lfu struct {
+	capacity int
+	size     int
+	freqs    map[int]*list.List
+	minFreq  int
+}
+
+func newLFU(capacity int) *lfu {
+	return &lfu{
+		capacity: capacity,
+		freqs:    make(map[int]*list.List),
+	}
+}
+
+func (l *lfu) access(string) {}
+
+func (l *lfu) add(e *entry) *entry {
+	var victim *entry
+	if l.size >= l.capacity {
+		victim = l.victim()
+		l.remove(victim)
+	}
+
+	e.freq = 1
+	e.elem = l.list(1).PushFront(e)
+	l.minFreq = 1
+	l.size++
+	return victim
+}
+
+func (l *lfu) touch(e *entry) {
+	l.unlink(e)
+	if l.minFreq == e.freq && l.freqs[e.freq] == nil {
+		l.minFreq++
+	}
+	e.freq++
+	e.elem = l.list(e.freq).PushFront(e)
+}
+
+func (l *lfu) remove(e *entry) {
+	l.unlink(e)
+	l.size--
+}
+
+func (l *lfu) evict() *entry {
+	if l.size == 0 {
+		return nil
+	}
+	victim := l.victim()
+	l.remove(victim)
+	return victim
+}
+
+// victim - самая давняя запись с наименьшей частотой
+func (l *lfu) victim() *entry {
+	if l.freqs[l.minFreq] == nil {
+		//минимальная частота устарела после удаления по сроку жизни
+		l.minFreq = 0
+		for freq := range l.freqs {
+			if l.minFreq == 0 || freq < l.minFreq {
+				l.minFreq = freq
+			}
+		}
+	}
+	return l.freqs[l.minFreq].Back().Value.(*entry)
+}
+
+// unlink - убирает запись из списка её частоты, пустые списки удаляются
+func (l *lfu) unlink(e *entry) {
+	lst := l.freqs[e.freq]
+	lst.Remove(e.elem)
+	if lst.Len() == 0 {
+		delete(l.freqs, e.freq)
+	}
+}
+
+func (l *lfu) list(freq int) *list.List {
+	lst, ok := l.freqs[freq]
+	if !ok {
+		lst = list.New()
+		l.freqs[freq] = lst
+	}
+	return lst
+}
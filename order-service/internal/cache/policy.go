@@ -0,0 +1,344 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"order-service/internal/models"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy - алгоритм вытеснения
+type Policy string
+
+const (
+	// PolicyLRU - вытесняется заказ, к которому дольше всего не обращались
+	PolicyLRU Policy = "lru"
+	// PolicyLFU - вытесняется заказ с наименьшим числом обращений, при равенстве - давний
+	PolicyLFU Policy = "lfu"
+	// PolicyTinyLFU - W-TinyLFU: новый заказ вытесняет старый, только если по оценке частоты он нужнее
+	PolicyTinyLFU Policy = "tinylfu"
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyLRU, PolicyLFU, PolicyTinyLFU:
+		return p, nil
+	case "":
+		return PolicyLRU, nil
+	}
+	return "", fmt.Errorf("unknown cache policy %q, want lru, lfu or tinylfu", s)
+}
+
+// evictor - алгоритм вытеснения PolicyCache. Методы вызываются под блокировкой кеша
+type evictor interface {
+	// access - обращение к заказу по ключу, в том числе промах
+	access(key string)
+	// add - новая запись. Если кеш переполнен, возвращает запись, которую нужно удалить, - это может быть и сама e
+	add(e *entry) *entry
+	// touch - попадание или обновление записи
+	touch(e *entry)
+	// remove - запись удалена из кеша не через add (срок жизни)
+	remove(e *entry)
+	// evict - убирает и возвращает следующую запись на вытеснение, nil - записей нет
+	evict() *entry
+}
+
+// entry - запись PolicyCache
+type entry struct {
+	key       string
+	order     *models.Order
+	expiresAt time.Time
+	size      int64
+	//положение записи в списках алгоритма вытеснения
+	elem *list.Element
+	//LFU - число обращений
+	freq int
+	//W-TinyLFU - сегмент, в котором лежит запись
+	segment segment
+}
+
+// PolicyCache - кеш заказов с подключаемым алгоритмом вытеснения, в остальном ведёт себя как LRUCache:
+// срок жизни записей, бюджет памяти, индекс по track_number, счётчики
+type PolicyCache struct {
+	mu         sync.Mutex
+	capacity   int
+	maxBytes   int64
+	bytes      int64
+	shared     *atomic.Int64
+	ttl        time.Duration
+	clock      Clock
+	newEvictor func(capacity int) evictor
+	evictor    evictor
+	entries    map[string]*entry
+	tracks     trackIndex
+
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+}
+
+// NewLFU - кеш, вытесняющий редко запрашиваемые заказы
+func NewLFU(opts Options) *PolicyCache {
+	return newPolicyCache(opts, func(capacity int) evictor { return newLFU(capacity) })
+}
+
+// NewTinyLFU - кеш с W-TinyLFU: небольшое LRU-окно для новых заказов и основная часть,
+// в которую заказ попадает, только если обращений к нему больше, чем к кандидату на вытеснение.
+// Разовые обращения не вымывают часто запрашиваемые заказы
+func NewTinyLFU(opts Options) *PolicyCache {
+	return newPolicyCache(opts, func(capacity int) evictor { return newTinyLFU(capacity) })
+}
+
+func newPolicyCache(opts Options, newEvictor func(capacity int) evictor) *PolicyCache {
+	clock := opts.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &PolicyCache{
+		capacity:   opts.Capacity,
+		maxBytes:   opts.MaxBytes,
+		shared:     opts.sharedBytes,
+		ttl:        opts.TTL,
+		clock:      clock,
+		newEvictor: newEvictor,
+		evictor:    newEvictor(opts.Capacity),
+		entries:    make(map[string]*entry),
+		tracks:     make(trackIndex),
+	}
+}
+
+func (c *PolicyCache) Set(order *models.Order) {
+	c.SetWithTTL(order, c.ttl)
+}
+
+// SetWithTTL - устанавливает заказ со своим сроком жизни; ttl <= 0 - запись не устаревает.
+// Заказ больше всего бюджета памяти не кешируется
+func (c *PolicyCache) SetWithTTL(order *models.Order, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := deadline(c.clock, ttl)
+	size := EstimateSize(order)
+	tooLarge := c.maxBytes > 0 && size > c.maxBytes
+
+	if e, exists := c.entries[order.OrderUID]; exists {
+		if tooLarge {
+			//старая версия не должна остаться в кеше
+			c.evictor.remove(e)
+			c.drop(e)
+			c.evictions++
+			return
+		}
+		c.tracks.remove(e.order)
+		e.order = order
+		e.expiresAt = expiresAt
+		c.addBytes(size - e.size)
+		e.size = size
+		c.tracks.add(order)
+		c.evictor.touch(e)
+		c.evictOverBudget()
+		return
+	}
+
+	if tooLarge {
+		return
+	}
+	c.insert(&entry{key: order.OrderUID, order: order, expiresAt: expiresAt, size: size})
+}
+
+func (c *PolicyCache) insert(e *entry) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.entries[e.key] = e
+	c.tracks.add(e.order)
+	c.addBytes(e.size)
+	if victim := c.evictor.add(e); victim != nil {
+		c.drop(victim)
+		c.evictions++
+	}
+	c.evictOverBudget()
+}
+
+// evictOverBudget - вытесняет записи в порядке алгоритма, пока оценка памяти больше бюджета.
+// Последняя запись не больше бюджета и остаётся; при общем бюджете остальное освобождает ShardedCache
+func (c *PolicyCache) evictOverBudget() {
+	for c.overBudget() && len(c.entries) > 1 {
+		if !c.evictVictim() {
+			return
+		}
+	}
+}
+
+// evictOne - вытесняет одну запись в порядке алгоритма, false - кеш пуст
+func (c *PolicyCache) evictOne() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.evictVictim()
+}
+
+func (c *PolicyCache) evictVictim() bool {
+	victim := c.evictor.evict()
+	if victim == nil {
+		return false
+	}
+	c.drop(victim)
+	c.evictions++
+	return true
+}
+
+func (c *PolicyCache) addBytes(delta int64) {
+	c.bytes += delta
+	if c.shared != nil {
+		c.shared.Add(delta)
+	}
+}
+
+func (c *PolicyCache) usedBytes() int64 {
+	if c.shared != nil {
+		return c.shared.Load()
+	}
+	return c.bytes
+}
+
+func (c *PolicyCache) overBudget() bool {
+	return c.maxBytes > 0 && c.usedBytes() > c.maxBytes
+}
+
+// drop - удаляет запись из мапы и индекса, из структур алгоритма она уже убрана
+func (c *PolicyCache) drop(e *entry) {
+	delete(c.entries, e.key)
+	c.tracks.remove(e.order)
+	c.addBytes(-e.size)
+}
+
+func (c *PolicyCache) Get(orderUID string) (*models.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lookup(orderUID)
+}
+
+func (c *PolicyCache) GetByTrack(trackNumber string) (*models.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	orderUID, exists := c.tracks[trackNumber]
+	if !exists {
+		c.misses++
+		return nil, false
+	}
+	return c.lookup(orderUID)
+}
+
+func (c *PolicyCache) lookup(orderUID string) (*models.Order, bool) {
+	c.evictor.access(orderUID)
+
+	e, exists := c.entries[orderUID]
+	if !exists {
+		c.misses++
+		return nil, false
+	}
+
+	if expired(e.expiresAt, c.clock.Now()) {
+		c.removeExpired(e)
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.evictor.touch(e)
+	return e.order, true
+}
+
+// peekTrack - заказ по track_number без учёта в счётчиках и без изменения порядка вытеснения
+func (c *PolicyCache) peekTrack(trackNumber string) (*models.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	orderUID, exists := c.tracks[trackNumber]
+	if !exists {
+		return nil, false
+	}
+
+	e := c.entries[orderUID]
+	if expired(e.expiresAt, c.clock.Now()) {
+		return nil, false
+	}
+	return e.order, true
+}
+
+// LoadBatch - заменяет содержимое кеша заказами из orders (до capacity)
+func (c *PolicyCache) LoadBatch(orders []*models.Order) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictor = c.newEvictor(c.capacity)
+	c.entries = make(map[string]*entry, len(orders))
+	c.tracks = make(trackIndex, len(orders))
+	c.addBytes(-c.bytes)
+
+	expiresAt := deadline(c.clock, c.ttl)
+	for _, order := range orders {
+		if len(c.entries) >= c.capacity {
+			break
+		}
+		if _, exists := c.entries[order.OrderUID]; exists {
+			continue
+		}
+		size := EstimateSize(order)
+		if c.maxBytes > 0 && c.usedBytes()+size > c.maxBytes {
+			break
+		}
+		c.insert(&entry{key: order.OrderUID, order: order, expiresAt: expiresAt, size: size})
+	}
+}
+
+func (c *PolicyCache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+		Size:        len(c.entries),
+		Capacity:    c.capacity,
+		Bytes:       c.bytes,
+		MaxBytes:    c.maxBytes,
+	}
+}
+
+// DeleteExpired - удаляет все устаревшие записи и возвращает их число
+func (c *PolicyCache) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	deleted := 0
+	for _, e := range c.entries {
+		if expired(e.expiresAt, now) {
+			c.removeExpired(e)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// RunJanitor - раз в interval удаляет устаревшие записи, пока не отменён ctx
+func (c *PolicyCache) RunJanitor(ctx context.Context, interval time.Duration) {
+	runJanitor(ctx, c.ttl, interval, c.DeleteExpired)
+}
+
+func (c *PolicyCache) removeExpired(e *entry) {
+	c.evictor.remove(e)
+	c.drop(e)
+	c.expirations++
+}
@@ -0,0 +1,278 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/service"
+)
+
+var _ service.OrderCache = (*PolicyCache)(nil)
+
+// policyConstructors - кеши с подключаемым вытеснением, общие сценарии проверяются для каждого
+var policyConstructors = map[string]func(Options) *PolicyCache{
+	"lfu":     NewLFU,
+	"tinylfu": NewTinyLFU,
+}
+
+func TestParsePolicy(t *testing.T) {
+	for in, want := range map[string]Policy{"": PolicyLRU, "lru": PolicyLRU, "lfu": PolicyLFU, "tinylfu": PolicyTinyLFU} {
+		got, err := ParsePolicy(in)
+		if err != nil || got != want {
+			t.Fatalf("ParsePolicy(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParsePolicy("arc"); err == nil {
+		t.Fatal("unknown policy must fail")
+	}
+}
+
+func TestPolicyCacheBasics(t *testing.T) {
+	for name, newCache := range policyConstructors {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(Options{Capacity: 2})
+
+			o1 := makeTrackedOrder("1", "TRK-1")
+			c.Set(o1)
+			if got, ok := c.Get("1"); !ok || got != o1 {
+				t.Fatal("cannot retrieve just-set order")
+			}
+			if got, ok := c.GetByTrack("TRK-1"); !ok || got != o1 {
+				t.Fatal("cannot retrieve order by track number")
+			}
+
+			//обновление заменяет заказ и индекс по трек-номеру
+			c.Set(makeTrackedOrder("1", "TRK-NEW"))
+			if _, ok := c.GetByTrack("TRK-1"); ok {
+				t.Fatal("old track number must be removed from index")
+			}
+			if got, ok := c.GetByTrack("TRK-NEW"); !ok || got.OrderUID != "1" {
+				t.Fatal("new track number must point to the order")
+			}
+
+			for i := 0; i < 10; i++ {
+				c.Set(makeOrder(fmt.Sprintf("x-%d", i)))
+			}
+			st := c.Stats()
+			if st.Size > 2 || len(c.entries) != st.Size {
+				t.Fatalf("capacity must be respected, got %+v", st)
+			}
+			if st.Evictions != uint64(11-st.Size) {
+				t.Fatalf("want %d evictions, got %+v", 11-st.Size, st)
+			}
+			if len(c.tracks) > st.Size {
+				t.Fatal("evicted orders must be removed from track index")
+			}
+		})
+	}
+}
+
+func TestPolicyCacheTTL(t *testing.T) {
+	for name, newCache := range policyConstructors {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+			c := newCache(Options{Capacity: 10, TTL: time.Minute, Clock: clock})
+
+			c.Set(makeTrackedOrder("1", "TRK-1"))
+			c.Set(makeOrder("2"))
+			c.SetWithTTL(makeOrder("forever"), 0)
+
+			clock.Advance(time.Minute)
+			if _, ok := c.GetByTrack("TRK-1"); ok {
+				t.Fatal("order must expire after TTL")
+			}
+			if n := c.DeleteExpired(); n != 1 {
+				t.Fatalf("want 1 expired, got %d", n)
+			}
+			if _, ok := c.Get("forever"); !ok {
+				t.Fatal("entry without TTL must not expire")
+			}
+
+			want := Stats{Hits: 1, Misses: 1, Expirations: 2, Size: 1, Capacity: 10, Bytes: EstimateSize(makeOrder("forever"))}
+			if got := c.Stats(); got != want {
+				t.Fatalf("want %+v, got %+v", want, got)
+			}
+
+			//после удаления по сроку жизни кеш продолжает вытеснять корректно
+			for i := 0; i < 20; i++ {
+				c.Set(makeOrder(fmt.Sprintf("x-%d", i)))
+			}
+			if got := c.Stats().Size; got != 10 {
+				t.Fatalf("want full cache, got size %d", got)
+			}
+		})
+	}
+}
+
+func TestPolicyCacheLoadBatch(t *testing.T) {
+	for name, newCache := range policyConstructors {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(Options{Capacity: 3})
+			c.Set(makeTrackedOrder("OLD", "TRK-OLD"))
+
+			c.LoadBatch([]*models.Order{makeOrder("A"), makeOrder("B"), makeOrder("C"), makeOrder("D")})
+			if _, ok := c.GetByTrack("TRK-OLD"); ok {
+				t.Fatal("old data must be replaced")
+			}
+			for _, uid := range []string{"A", "B", "C"} {
+				if _, ok := c.Get(uid); !ok {
+					t.Fatalf("%s must be loaded", uid)
+				}
+			}
+			if _, ok := c.Get("D"); ok {
+				t.Fatal("capacity must be respected")
+			}
+		})
+	}
+}
+
+func TestLFUEvictsLeastFrequent(t *testing.T) {
+	c := NewLFU(Options{Capacity: 3})
+
+	c.Set(makeOrder("hot"))
+	c.Set(makeOrder("warm"))
+	c.Set(makeOrder("cold"))
+	for i := 0; i < 3; i++ {
+		c.Get("hot")
+	}
+	c.Get("warm")
+
+	c.Set(makeOrder("new")) // вытесняет cold: к нему не обращались
+	if _, ok := c.Get("cold"); ok {
+		t.Fatal("least frequently used order must be evicted")
+	}
+
+	//при равной частоте вытесняется давний: new и cold-2 по одному обращению, new старше
+	c.Set(makeOrder("cold-2"))
+	if _, ok := c.Get("new"); ok {
+		t.Fatal("older order must be evicted on equal frequency")
+	}
+	for _, uid := range []string{"hot", "warm", "cold-2"} {
+		if _, ok := c.Get(uid); !ok {
+			t.Fatalf("%s must stay", uid)
+		}
+	}
+}
+
+// scanResistance - горячий набор, затем поток разовых обращений, которые сервис кладёт в кеш после промаха
+func scanResistance(c interface {
+	Get(string) (*models.Order, bool)
+	Set(*models.Order)
+}) int {
+	hot := make([]string, 50)
+	for i := range hot {
+		hot[i] = fmt.Sprintf("hot-%d", i)
+	}
+	for round := 0; round < 5; round++ {
+		for _, uid := range hot {
+			if _, ok := c.Get(uid); !ok {
+				c.Set(makeOrder(uid))
+			}
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		uid := fmt.Sprintf("scan-%d", i)
+		if _, ok := c.Get(uid); !ok {
+			c.Set(makeOrder(uid))
+		}
+	}
+
+	survived := 0
+	for _, uid := range hot {
+		if _, ok := c.Get(uid); ok {
+			survived++
+		}
+	}
+	return survived
+}
+
+func TestTinyLFUScanResistance(t *testing.T) {
+	if got := scanResistance(NewLRUCache(100)); got != 0 {
+		t.Fatalf("LRU is expected to lose the hot set on a scan, %d survived", got)
+	}
+	if got := scanResistance(NewTinyLFU(Options{Capacity: 100})); got != 50 {
+		t.Fatalf("W-TinyLFU must keep the hot set on a scan, %d of 50 survived", got)
+	}
+}
+
+func TestTinyLFUAdmitsFrequent(t *testing.T) {
+	c := NewTinyLFU(Options{Capacity: 100})
+	for i := 0; i < 100; i++ {
+		c.Set(makeOrder(fmt.Sprintf("old-%d", i)))
+	}
+
+	//заказ, который часто запрашивают, попадает в кеш, несмотря на заполненную основную часть
+	for i := 0; i < 5; i++ {
+		c.Get("popular")
+	}
+	c.Set(makeOrder("popular"))
+	c.Set(makeOrder("pusher")) // вытесняет popular из окна в основную часть
+
+	if _, ok := c.Get("popular"); !ok {
+		t.Fatal("frequently requested order must be admitted")
+	}
+	if got := c.Stats().Size; got != 100 {
+		t.Fatalf("want size 100, got %d", got)
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := newCountMinSketch(16)
+
+	for i := 0; i < 5; i++ {
+		s.increment("a")
+	}
+	s.increment("b")
+	if got := s.estimate("a"); got < 5 {
+		t.Fatalf("estimate must not be lower than the real count, got %d", got)
+	}
+	if got := s.estimate("b"); got < 1 {
+		t.Fatalf("estimate must not be lower than the real count, got %d", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		s.increment("a")
+	}
+	if got := s.estimate("a"); got > 15 {
+		t.Fatalf("counters must saturate at 15, got %d", got)
+	}
+
+	//каждые 10*width обращений частоты делятся пополам
+	s = newCountMinSketch(16)
+	for i := 0; i < s.resetAt; i++ {
+		s.increment("a")
+	}
+	if got := s.estimate("a"); got != 7 {
+		t.Fatalf("frequencies must be halved after resetAt additions, got %d", got)
+	}
+}
+
+func TestShardedPolicy(t *testing.T) {
+	for _, p := range []Policy{PolicyLRU, PolicyLFU, PolicyTinyLFU} {
+		t.Run(string(p), func(t *testing.T) {
+			c := NewSharded(Options{Capacity: 64, Policy: p}, 4)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 500; i++ {
+				wg.Add(1)
+				go func(id int) {
+					defer wg.Done()
+					key := fmt.Sprintf("key-%d", id%200)
+					c.Set(makeTrackedOrder(key, "TRK-"+key))
+					c.Get(key)
+					c.GetByTrack("TRK-" + key)
+				}(i)
+			}
+			wg.Wait()
+
+			st := c.Stats()
+			if st.Size > st.Capacity || st.Capacity != 64 {
+				t.Fatalf("capacity must be respected, got %+v", st)
+			}
+		})
+	}
+}
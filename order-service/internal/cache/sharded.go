@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"order-service/internal/models"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShards - число шардов по умолчанию
+const DefaultShards = 16
+
+// shardCache - кеш одного шарда: LRUCache или PolicyCache
+type shardCache interface {
+	Set(*models.Order)
+	SetWithTTL(*models.Order, time.Duration)
+	Get(string) (*models.Order, bool)
+	GetByTrack(string) (*models.Order, bool)
+	peekTrack(string) (*models.Order, bool)
+	LoadBatch([]*models.Order)
+	Stats() Stats
+	DeleteExpired() int
+	evictOne() bool
+}
+
+// ShardedCache - кеш из нескольких кешей со своими мьютексами: заказ попадает в шард по хешу order_uid,
+// поэтому параллельные чтения разных заказов не ждут друг друга. Вытеснение и capacity - внутри шарда
+type ShardedCache struct {
+	shards []shardCache
+	mask   uint32
+	ttl    time.Duration
+	//промахи GetByTrack считаются здесь: трек-номер ищется во всех шардах
+	trackMisses atomic.Uint64
+
+	//бюджет памяти общий для всех шардов: шарды ведут общий счётчик байт
+	maxBytes int64
+	bytes    atomic.Int64
+	//шард, с которого начнётся следующее вытеснение по бюджету
+	evictCursor atomic.Uint32
+}
+
+// NewSharded - шардированный кеш на opts.Capacity заказов с алгоритмом вытеснения opts.Policy.
+// Число шардов округляется вверх до степени двойки, capacity делится между шардами поровну.
+// Бюджет памяти общий: заказ кешируется, если помещается в весь MaxBytes, а не в его долю на шард
+func NewSharded(opts Options, shards int) *ShardedCache {
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+
+	perShard := (opts.Capacity + n - 1) / n
+	c := &ShardedCache{
+		shards: make([]shardCache, n),
+		mask:   uint32(n - 1),
+		ttl:    opts.TTL,
+
+		maxBytes: opts.MaxBytes,
+	}
+	shardOpts := Options{Capacity: perShard, TTL: opts.TTL, Clock: opts.Clock}
+	if opts.MaxBytes > 0 {
+		shardOpts.MaxBytes = opts.MaxBytes
+		shardOpts.sharedBytes = &c.bytes
+	}
+	for i := range c.shards {
+		switch opts.Policy {
+		case PolicyLFU:
+			c.shards[i] = NewLFU(shardOpts)
+		case PolicyTinyLFU:
+			c.shards[i] = NewTinyLFU(shardOpts)
+		default:
+			c.shards[i] = New(shardOpts)
+		}
+	}
+	return c
+}
+
+func (c *ShardedCache) shard(orderUID string) shardCache {
+	return c.shards[c.index(orderUID)]
+}
+
+// index - номер шарда по FNV-1a от order_uid, без аллокаций
+func (c *ShardedCache) index(orderUID string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(orderUID); i++ {
+		h ^= uint32(orderUID[i])
+		h *= 16777619
+	}
+	return h & c.mask
+}
+
+func (c *ShardedCache) Set(order *models.Order) {
+	c.shard(order.OrderUID).Set(order)
+	c.evictOverBudget()
+}
+
+func (c *ShardedCache) SetWithTTL(order *models.Order, ttl time.Duration) {
+	c.shard(order.OrderUID).SetWithTTL(order, ttl)
+	c.evictOverBudget()
+}
+
+// evictOverBudget - шард записи сначала вытесняет свои записи, а если их не хватило, здесь освобождается
+// место в остальных шардах по очереди. Каждый шард вытесняет по своему алгоритму, поэтому глобальный
+// порядок вытеснения приблизительный. Параллельные записи могут ненадолго превысить бюджет
+func (c *ShardedCache) evictOverBudget() {
+	for c.maxBytes > 0 && c.bytes.Load() > c.maxBytes {
+		evicted := false
+		for range c.shards {
+			s := c.shards[c.evictCursor.Add(1)&c.mask]
+			if s.evictOne() {
+				evicted = true
+				if c.bytes.Load() <= c.maxBytes {
+					return
+				}
+			}
+		}
+		if !evicted {
+			return
+		}
+	}
+}
+
+func (c *ShardedCache) Get(orderUID string) (*models.Order, bool) {
+	return c.shard(orderUID).Get(orderUID)
+}
+
+// GetByTrack - трек-номер не определяет шард, поэтому ищется во всех.
+// Если трек-номер есть у нескольких заказов, возвращается самый новый, как в GetOrderByTrackNumber
+func (c *ShardedCache) GetByTrack(trackNumber string) (*models.Order, bool) {
+	var found *models.Order
+	var from shardCache
+	for _, s := range c.shards {
+		order, ok := s.peekTrack(trackNumber)
+		if ok && (found == nil || order.DateCreated.After(found.DateCreated)) {
+			found, from = order, s
+		}
+	}
+
+	if found == nil {
+		c.trackMisses.Add(1)
+		return nil, false
+	}
+
+	//попадание засчитывается и продвигает заказ в LRU его шарда
+	return from.GetByTrack(trackNumber)
+}
+
+// LoadBatch - раскладывает заказы по шардам и загружает каждый шард заново.
+// При бюджете памяти загружаются первые заказы из orders, которые в него помещаются
+func (c *ShardedCache) LoadBatch(orders []*models.Order) {
+	if c.maxBytes > 0 {
+		//шарды очищаются до загрузки, иначе старые записи ещё не загруженных шардов занимали бы бюджет
+		for _, s := range c.shards {
+			s.LoadBatch(nil)
+		}
+		var total int64
+		for i, order := range orders {
+			if total += EstimateSize(order); total > c.maxBytes {
+				orders = orders[:i]
+				break
+			}
+		}
+	}
+
+	batches := make([][]*models.Order, len(c.shards))
+	for _, order := range orders {
+		i := c.index(order.OrderUID)
+		batches[i] = append(batches[i], order)
+	}
+	for i, s := range c.shards {
+		s.LoadBatch(batches[i])
+	}
+}
+
+// Stats - сумма счётчиков всех шардов
+func (c *ShardedCache) Stats() Stats {
+	var total Stats
+	for _, s := range c.shards {
+		st := s.Stats()
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Evictions += st.Evictions
+		total.Expirations += st.Expirations
+		total.Size += st.Size
+		total.Capacity += st.Capacity
+		total.Bytes += st.Bytes
+	}
+	total.MaxBytes = c.maxBytes
+	total.Misses += c.trackMisses.Load()
+	return total
+}
+
+// DeleteExpired - удаляет устаревшие записи во всех шардах по очереди, не блокируя весь кеш сразу
+func (c *ShardedCache) DeleteExpired() int {
+	deleted := 0
+	for _, s := range c.shards {
+		deleted += s.DeleteExpired()
+	}
+	return deleted
+}
+
+// RunJanitor - то же, что LRUCache.RunJanitor, для всех шардов
+func (c *ShardedCache) RunJanitor(ctx context.Context, interval time.Duration) {
+	runJanitor(ctx, c.ttl, interval, c.DeleteExpired)
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/service"
+)
+
+var _ service.OrderCache = (*ShardedCache)(nil)
+
+func TestNewSharded(t *testing.T) {
+	c := NewSharded(Options{Capacity: 100}, 3)
+
+	if len(c.shards) != 4 {
+		t.Fatalf("shards must be rounded up to a power of two, got %d", len(c.shards))
+	}
+	if got := c.Stats().Capacity; got != 100 {
+		t.Fatalf("want total capacity 100, got %d", got)
+	}
+
+	if one := NewSharded(Options{Capacity: 10}, 0); len(one.shards) != 1 {
+		t.Fatal("zero shards must mean a single shard")
+	}
+}
+
+func TestShardedSetAndGet(t *testing.T) {
+	c := NewSharded(Options{Capacity: 1000}, 8)
+
+	for i := 0; i < 100; i++ {
+		c.Set(makeOrder(fmt.Sprintf("uid-%d", i)))
+	}
+	for i := 0; i < 100; i++ {
+		uid := fmt.Sprintf("uid-%d", i)
+		if got, ok := c.Get(uid); !ok || got.OrderUID != uid {
+			t.Fatalf("cannot retrieve %s", uid)
+		}
+	}
+
+	//заказы разошлись по шардам
+	used := 0
+	for _, s := range c.shards {
+		if s.Stats().Size > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Fatalf("orders must be spread across shards, used %d", used)
+	}
+
+	c.Get("unknown")
+	st := c.Stats()
+	if st.Hits != 100 || st.Misses != 1 || st.Size != 100 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestShardedEvictionPerShard(t *testing.T) {
+	c := NewSharded(Options{Capacity: 4}, 2)
+
+	for i := 0; i < 20; i++ {
+		c.Set(makeOrder(fmt.Sprintf("uid-%d", i)))
+	}
+
+	st := c.Stats()
+	if st.Size > st.Capacity {
+		t.Fatalf("size %d exceeds capacity %d", st.Size, st.Capacity)
+	}
+	if st.Evictions != uint64(20-st.Size) {
+		t.Fatalf("want %d evictions, got %d", 20-st.Size, st.Evictions)
+	}
+}
+
+func TestShardedGetByTrack(t *testing.T) {
+	c := NewSharded(Options{Capacity: 100}, 8)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		o := makeTrackedOrder(fmt.Sprintf("uid-%d", i), fmt.Sprintf("TRK-%d", i))
+		o.DateCreated = base
+		c.Set(o)
+	}
+
+	//трек-номер повторяется в разных шардах: отдаётся самый новый заказ
+	newest := makeTrackedOrder("uid-new", "TRK-3")
+	newest.DateCreated = base.Add(time.Hour)
+	c.Set(newest)
+
+	for i := 0; i < 10; i++ {
+		if _, ok := c.GetByTrack(fmt.Sprintf("TRK-%d", i)); !ok {
+			t.Fatalf("cannot retrieve TRK-%d", i)
+		}
+	}
+	if got, _ := c.GetByTrack("TRK-3"); got.OrderUID != "uid-new" {
+		t.Fatalf("want the newest order, got %s", got.OrderUID)
+	}
+
+	c.GetByTrack("TRK-unknown")
+	st := c.Stats()
+	if st.Hits != 11 || st.Misses != 1 {
+		t.Fatalf("track lookups must be counted once, got %+v", st)
+	}
+}
+
+func TestShardedLoadBatch(t *testing.T) {
+	c := NewSharded(Options{Capacity: 100}, 4)
+	c.Set(makeTrackedOrder("OLD", "TRK-OLD"))
+
+	orders := make([]*models.Order, 0, 20)
+	for i := 0; i < 20; i++ {
+		orders = append(orders, makeTrackedOrder(fmt.Sprintf("uid-%d", i), fmt.Sprintf("TRK-%d", i)))
+	}
+	c.LoadBatch(orders)
+
+	if _, ok := c.Get("OLD"); ok {
+		t.Fatal("old data must be replaced")
+	}
+	if _, ok := c.GetByTrack("TRK-OLD"); ok {
+		t.Fatal("track index must be rebuilt")
+	}
+	for _, o := range orders {
+		if _, ok := c.Get(o.OrderUID); !ok {
+			t.Fatalf("%s must be loaded", o.OrderUID)
+		}
+	}
+}
+
+func TestShardedTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := NewSharded(Options{Capacity: 100, TTL: time.Minute, Clock: clock}, 4)
+
+	for i := 0; i < 10; i++ {
+		c.Set(makeTrackedOrder(fmt.Sprintf("uid-%d", i), fmt.Sprintf("TRK-%d", i)))
+	}
+	c.SetWithTTL(makeOrder("forever"), 0)
+
+	clock.Advance(time.Minute)
+	if _, ok := c.GetByTrack("TRK-1"); ok {
+		t.Fatal("expired order must not be found by track number")
+	}
+	if n := c.DeleteExpired(); n != 10 {
+		t.Fatalf("want 10 expired, got %d", n)
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Fatal("entry without TTL must not expire")
+	}
+}
+
+func TestShardedConcurrentAccess(t *testing.T) {
+	c := NewSharded(Options{Capacity: 128}, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", id%300)
+			c.Set(makeTrackedOrder(key, "TRK-"+key))
+			c.Get(key)
+			c.GetByTrack("TRK-" + key)
+		}(i)
+	}
+	wg.Wait()
+
+	st := c.Stats()
+	if st.Size > st.Capacity {
+		t.Fatalf("size %d exceeds capacity %d", st.Size, st.Capacity)
+	}
+}
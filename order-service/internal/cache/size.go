@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"container/list"
+	"order-service/internal/models"
+	"unsafe"
+)
+
+var (
+	orderStructSize = int64(unsafe.Sizeof(models.Order{}))
+	itemStructSize  = int64(unsafe.Sizeof(models.Item{}))
+)
+
+// entryOverhead - примерная цена одной записи кеша без самого заказа: элемент списка, запись с ключом
+// и сроком жизни, ячейки мапы по order_uid и индекса по track_number (строки в них ссылаются на строки заказа)
+const entryOverhead = int64(unsafe.Sizeof(list.Element{})) + int64(unsafe.Sizeof(entry{})) + 64
+
+// EstimateSize - сколько байт заказ занимает в памяти кеша: структуры заказа и позиций, содержимое строк
+// и служебные структуры записи. Оценка приблизительная: не учитывает выравнивание аллокатора и общие строки
+func EstimateSize(o *models.Order) int64 {
+	size := entryOverhead + orderStructSize + int64(cap(o.Items))*itemStructSize
+	size += int64(len(o.OrderUID) + len(o.TrackNumber) + len(o.Entry) + len(o.Locale) +
+		len(o.InternalSignature) + len(o.CustomerID) + len(o.DeliveryService) + len(o.Shardkey) +
+		len(o.OofShard) + len(o.Status))
+
+	d := &o.Delivery
+	size += int64(len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) + len(d.Address) + len(d.Region) + len(d.Email))
+
+	p := &o.Payment
+	size += int64(len(p.Transaction) + len(p.RequestID) + len(p.Currency) + len(p.Provider) + len(p.Bank))
+
+	for i := range o.Items {
+		it := &o.Items[i]
+		size += int64(len(it.TrackNumber) + len(it.Rid) + len(it.Name) + len(it.Size) + len(it.Brand))
+	}
+	return size
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+)
+
+// makeSizedOrder - заказ с n позициями, чтобы оценка памяти росла вместе с n
+func makeSizedOrder(uid string, n int) *models.Order {
+	o := makeOrder(uid)
+	for i := 0; i < n; i++ {
+		o.Items = append(o.Items, models.Item{Name: fmt.Sprintf("item-%d", i), Brand: "brand"})
+	}
+	return o
+}
+
+func TestEstimateSize(t *testing.T) {
+	empty := EstimateSize(makeOrder("1"))
+	if empty <= entryOverhead+orderStructSize {
+		t.Fatalf("size must include struct and strings, got %d", empty)
+	}
+
+	withItems := EstimateSize(makeSizedOrder("1", 3))
+	if withItems < empty+3*itemStructSize {
+		t.Fatalf("size must grow with items, got %d for empty %d", withItems, empty)
+	}
+
+	long := makeOrder("1")
+	long.Delivery.Address = string(make([]byte, 1000))
+	if EstimateSize(long)-empty != 1000 {
+		t.Fatal("size must include string contents")
+	}
+}
+
+// budgetConstructors - все реализации кеша одного шарда, бюджет памяти проверяется для каждой
+var budgetConstructors = map[string]func(Options) shardCache{
+	"lru":     func(opts Options) shardCache { return New(opts) },
+	"lfu":     func(opts Options) shardCache { return NewLFU(opts) },
+	"tinylfu": func(opts Options) shardCache { return NewTinyLFU(opts) },
+}
+
+func TestMaxBytesEvictsUntilUnderBudget(t *testing.T) {
+	size := EstimateSize(makeSizedOrder("0", 2))
+	for name, newCache := range budgetConstructors {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(Options{Capacity: 100, MaxBytes: 3 * size})
+
+			for i := 0; i < 10; i++ {
+				c.Set(makeSizedOrder(fmt.Sprint(i), 2))
+			}
+
+			st := c.Stats()
+			if st.Size != 3 || st.Bytes != 3*size || st.MaxBytes != 3*size {
+				t.Fatalf("want 3 orders within budget, got %+v", st)
+			}
+			if st.Evictions != 7 {
+				t.Fatalf("want 7 evictions, got %+v", st)
+			}
+			if _, ok := c.Get("9"); !ok {
+				t.Fatal("just written order must stay in cache")
+			}
+		})
+	}
+}
+
+func TestMaxBytesLargeOrderEvictsSeveral(t *testing.T) {
+	small := EstimateSize(makeOrder("0"))
+	for name, newCache := range budgetConstructors {
+		t.Run(name, func(t *testing.T) {
+			big := makeSizedOrder("big", 10)
+			c := newCache(Options{Capacity: 100, MaxBytes: EstimateSize(big) + small})
+
+			for i := 0; i < 5; i++ {
+				c.Set(makeOrder(fmt.Sprint(i)))
+			}
+			c.Set(big)
+
+			st := c.Stats()
+			if st.Bytes > st.MaxBytes {
+				t.Fatalf("bytes must not exceed budget, got %+v", st)
+			}
+			if st.Evictions < 4 {
+				t.Fatalf("large order must evict several small ones, got %+v", st)
+			}
+			if _, ok := c.Get("big"); !ok {
+				t.Fatal("large order must be cached")
+			}
+		})
+	}
+}
+
+func TestMaxBytesTooLargeOrder(t *testing.T) {
+	for name, newCache := range budgetConstructors {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(Options{Capacity: 10, MaxBytes: EstimateSize(makeOrder("1")) * 2})
+
+			c.Set(makeOrder("1"))
+			c.Set(makeSizedOrder("huge", 100))
+			if _, ok := c.Get("huge"); ok {
+				t.Fatal("order larger than budget must not be cached")
+			}
+			if _, ok := c.Get("1"); !ok {
+				t.Fatal("too large order must not evict others")
+			}
+
+			//новая версия больше бюджета - старая не должна отдаваться из кеша
+			c.Set(makeSizedOrder("1", 100))
+			if _, ok := c.Get("1"); ok {
+				t.Fatal("stale version must be removed")
+			}
+			if st := c.Stats(); st.Size != 0 || st.Bytes != 0 {
+				t.Fatalf("want empty cache, got %+v", st)
+			}
+		})
+	}
+}
+
+func TestMaxBytesAccounting(t *testing.T) {
+	for name, newCache := range budgetConstructors {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+			c := newCache(Options{Capacity: 10, TTL: time.Minute, Clock: clock})
+
+			c.Set(makeOrder("1"))
+			c.Set(makeSizedOrder("2", 1))
+			//обновление заменяет оценку старой версии
+			c.Set(makeSizedOrder("2", 5))
+
+			want := EstimateSize(makeOrder("1")) + EstimateSize(makeSizedOrder("2", 5))
+			if got := c.Stats().Bytes; got != want {
+				t.Fatalf("want %d bytes after update, got %d", want, got)
+			}
+
+			clock.Advance(time.Minute)
+			c.DeleteExpired()
+			if st := c.Stats(); st.Bytes != 0 || st.MaxBytes != 0 {
+				t.Fatalf("expired entries must release bytes, got %+v", st)
+			}
+		})
+	}
+}
+
+func TestMaxBytesLoadBatch(t *testing.T) {
+	size := EstimateSize(makeOrder("0"))
+	for name, newCache := range budgetConstructors {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(Options{Capacity: 100, MaxBytes: 2 * size})
+
+			c.LoadBatch([]*models.Order{makeOrder("0"), makeOrder("1"), makeOrder("2")})
+
+			if st := c.Stats(); st.Size != 2 || st.Bytes != 2*size {
+				t.Fatalf("batch must stop at budget, got %+v", st)
+			}
+		})
+	}
+}
+
+func TestShardedMaxBytes(t *testing.T) {
+	c := NewSharded(Options{Capacity: 1000, MaxBytes: 10_000}, 4)
+
+	if got := c.Stats().MaxBytes; got != 10_000 {
+		t.Fatalf("want total budget 10000, got %d", got)
+	}
+
+	for i := 0; i < 200; i++ {
+		c.Set(makeSizedOrder(fmt.Sprint(i), 1))
+	}
+
+	st := c.Stats()
+	if st.Bytes > st.MaxBytes || st.Bytes == 0 {
+		t.Fatalf("bytes must stay within budget, got %+v", st)
+	}
+	if st.Evictions == 0 {
+		t.Fatal("budget must evict orders")
+	}
+}
+
+func TestShardedMaxBytesIsGlobal(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, PolicyLFU, PolicyTinyLFU} {
+		t.Run(string(policy), func(t *testing.T) {
+			small := EstimateSize(makeOrder("0"))
+			big := makeSizedOrder("big", 20)
+			budget := EstimateSize(big) + 4*small
+			c := NewSharded(Options{Capacity: 1000, MaxBytes: budget, Policy: policy}, 16)
+
+			for i := 0; i < 50; i++ {
+				c.Set(makeOrder(fmt.Sprint(i)))
+			}
+			//заказ больше доли шарда (budget/16), но меньше всего бюджета, должен кешироваться
+			c.Set(big)
+
+			if _, ok := c.Get("big"); !ok {
+				t.Fatal("order within the global budget must be cached")
+			}
+			st := c.Stats()
+			if st.Bytes > budget || st.MaxBytes != budget {
+				t.Fatalf("bytes must stay within the global budget, got %+v", st)
+			}
+			if st.Bytes != c.bytes.Load() {
+				t.Fatalf("shared counter %d must match shards %d", c.bytes.Load(), st.Bytes)
+			}
+		})
+	}
+}
+
+func TestShardedMaxBytesLoadBatch(t *testing.T) {
+	size := EstimateSize(makeOrder("00"))
+	c := NewSharded(Options{Capacity: 1000, MaxBytes: 10 * size}, 4)
+
+	c.Set(makeOrder("old"))
+	orders := make([]*models.Order, 20)
+	for i := range orders {
+		orders[i] = makeOrder(fmt.Sprintf("%02d", i))
+	}
+	c.LoadBatch(orders)
+
+	//загружаются первые заказы, которые помещаются в бюджет; старые записи не занимают места
+	st := c.Stats()
+	if st.Size != 10 || st.Bytes != 10*size || c.bytes.Load() != 10*size {
+		t.Fatalf("want first 10 orders, got %+v", st)
+	}
+	for _, o := range orders[:10] {
+		if _, ok := c.Get(o.OrderUID); !ok {
+			t.Fatalf("order %s must be preloaded", o.OrderUID)
+		}
+	}
+}
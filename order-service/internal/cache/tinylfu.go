@@ -0,0 +1,196 @@
+package cache
+
+import "container/list"
+
+// segment - часть W-TinyLFU, в которой лежит запись
+type segment uint8
+
+const (
+	segmentWindow segment = iota
+	segmentProbation
+	segmentProtected
+)
+
+// tinyLFU - W-TinyLFU. Новые записи попадают в LRU-окно (1% capacity). Вытесненная из окна запись
+// становится кандидатом в основную часть (SLRU: probation и protected, 80% основной части) и допускается туда,
+// только если по скетчу частот к ней обращались чаще, чем к записи, которую она вытеснит.
+// Иначе вытесняется сам кандидат: разовые обращения не вымывают часто запрашиваемые заказы
+type tinyLFU struct {
+	sketch *countMinSketch
+
+	window    *list.List
+	probation *list.List
+	protected *list.List
+
+	windowCap    int
+	mainCap      int
+	protectedCap int
+}
+
+func newTinyLFU(capacity int) *tinyLFU {
+	windowCap := max(1, capacity/100)
+	mainCap := max(0, capacity-windowCap)
+	return &tinyLFU{
+		sketch:       newCountMinSketch(capacity),
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+	}
+}
+
+func (t *tinyLFU) access(key string) {
+	t.sketch.increment(key)
+}
+
+func (t *tinyLFU) add(e *entry) *entry {
+	e.segment = segmentWindow
+	e.elem = t.window.PushFront(e)
+	if t.window.Len() <= t.windowCap {
+		return nil
+	}
+
+	candidate := t.window.Remove(t.window.Back()).(*entry)
+	if t.probation.Len()+t.protected.Len() < t.mainCap {
+		t.pushProbation(candidate)
+		return nil
+	}
+
+	victim := t.mainVictim()
+	if victim == nil || t.sketch.estimate(candidate.key) <= t.sketch.estimate(victim.key) {
+		return candidate
+	}
+
+	t.remove(victim)
+	t.pushProbation(candidate)
+	return victim
+}
+
+func (t *tinyLFU) touch(e *entry) {
+	switch e.segment {
+	case segmentWindow:
+		t.window.MoveToFront(e.elem)
+	case segmentProtected:
+		t.protected.MoveToFront(e.elem)
+	case segmentProbation:
+		//повторное обращение переводит запись в protected, лишняя запись protected возвращается в probation
+		t.probation.Remove(e.elem)
+		e.segment = segmentProtected
+		e.elem = t.protected.PushFront(e)
+		if t.protected.Len() > t.protectedCap {
+			demoted := t.protected.Remove(t.protected.Back()).(*entry)
+			t.pushProbation(demoted)
+		}
+	}
+}
+
+func (t *tinyLFU) remove(e *entry) {
+	switch e.segment {
+	case segmentWindow:
+		t.window.Remove(e.elem)
+	case segmentProbation:
+		t.probation.Remove(e.elem)
+	case segmentProtected:
+		t.protected.Remove(e.elem)
+	}
+}
+
+// evict - для бюджета памяти: сначала основная часть, как при допуске, затем окно
+func (t *tinyLFU) evict() *entry {
+	victim := t.mainVictim()
+	if victim == nil {
+		back := t.window.Back()
+		if back == nil {
+			return nil
+		}
+		victim = back.Value.(*entry)
+	}
+	t.remove(victim)
+	return victim
+}
+
+func (t *tinyLFU) pushProbation(e *entry) {
+	e.segment = segmentProbation
+	e.elem = t.probation.PushFront(e)
+}
+
+// mainVictim - кандидат на вытеснение из основной части: давняя запись probation, если её нет - protected
+func (t *tinyLFU) mainVictim() *entry {
+	if back := t.probation.Back(); back != nil {
+		return back.Value.(*entry)
+	}
+	if back := t.protected.Back(); back != nil {
+		return back.Value.(*entry)
+	}
+	return nil
+}
+
+// countMinSketch - приблизительная частота обращений к ключам в фиксированной памяти.
+// Четыре ряда счётчиков до 15; оценка - минимум по рядам, поэтому коллизии только завышают частоту.
+// После 10*width обращений все счётчики делятся пополам, чтобы старая популярность со временем забывалась
+type countMinSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 16
+	for width < capacity {
+		width <<= 1
+	}
+	s := &countMinSketch{
+		mask:    uint64(width - 1),
+		resetAt: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *countMinSketch) increment(key string) {
+	h1, h2 := sketchHash(key)
+	for i := range s.rows {
+		idx := (h1 + uint64(i)*h2) & s.mask
+		if s.rows[i][idx] < 15 {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.halve()
+	}
+}
+
+func (s *countMinSketch) estimate(key string) uint8 {
+	h1, h2 := sketchHash(key)
+	est := uint8(15)
+	for i := range s.rows {
+		est = min(est, s.rows[i][(h1+uint64(i)*h2)&s.mask])
+	}
+	return est
+}
+
+func (s *countMinSketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// sketchHash - FNV-1a, из которого получаются два независимых хеша для индексов рядов
+func sketchHash(key string) (uint64, uint64) {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h, (h >> 32) | 1
+}
@@ -10,27 +10,51 @@ import (
 
 type Config struct {
 	HTTPServer HTTPServer
+	GRPCServer GRPCServer
 	Cache      CacheConfig
 	Postgres   PostgresConfig
 	Kafka      KafkaConfig
+	Order      OrderConfig
+	Validation ValidationConfig
+	Feed       FeedConfig
+	Outbox     OutboxConfig
+
+	// сколько ждать завершения HTTP-запросов и обрабатываемых сообщений при остановке
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" env-default:"30s"`
 }
 
 type HTTPServer struct {
 	Address     string        `env:"HTTP_ADDRESS"`
 	Timeout     time.Duration `env:"HTTP_TIMEOUT"`
 	IdleTimeout time.Duration `env:"HTTP_IDLE_TIMEOUT"`
+	// сколько ждать ответа одной зависимости в /readyz
+	HealthTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" env-default:"2s"`
+}
+type GRPCServer struct {
+	// адрес gRPC API; пустой - gRPC-сервер не запускается
+	Address string `env:"GRPC_ADDRESS" env-default:":9090"`
 }
 type CacheConfig struct {
 	CacheCapacity     int `env:"CACHE_CAPACITY"`
 	CachePreloadLimit int `env:"CACHE_PRELOAD_LIMIT"`
+	// срок жизни заказа в кеше, чтобы не отдавать устаревший статус бесконечно; 0 - без ограничения
+	TTL time.Duration `env:"CACHE_TTL" env-default:"10m"`
+	// как часто удалять устаревшие заказы, к которым больше не обращаются
+	JanitorInterval time.Duration `env:"CACHE_JANITOR_INTERVAL" env-default:"1m"`
+	// число шардов со своими блокировками, округляется до степени двойки; 1 - один общий LRU
+	Shards int `env:"CACHE_SHARDS" env-default:"16"`
+	// алгоритм вытеснения: lru, lfu или tinylfu
+	Policy string `env:"CACHE_POLICY" env-default:"lru"`
+	// бюджет памяти кеша в байтах по оценке размера заказов, общий для всех шардов; 0 - только ограничение по CACHE_CAPACITY
+	MaxBytes int64 `env:"CACHE_MAX_BYTES" env-default:"0"`
 }
 type PostgresConfig struct {
-	Host        string `env:"DB_HOST"`
-	Port        string `env:"DB_PORT"`
-	User        string `env:"DB_USER"`
-	Password    string `env:"DB_PASSWORD"`
-	DBName      string `env:"DB_NAME"`
-	MaxConns    int32  `env:"DB_MAX_CONNS" env-default:"20"`
+	Host     string `env:"DB_HOST"`
+	Port     string `env:"DB_PORT"`
+	User     string `env:"DB_USER"`
+	Password string `env:"DB_PASSWORD"`
+	DBName   string `env:"DB_NAME"`
+	MaxConns int32  `env:"DB_MAX_CONNS" env-default:"20"`
 }
 
 type KafkaConfig struct {
@@ -42,6 +66,49 @@ type KafkaConfig struct {
 	MaxWait  time.Duration `env:"KAFKA_MAX_WAIT"`
 	Timeout  time.Duration `env:"KAFKA_TIMEOUT"`
 	DLQTopic string        `env:"KAFKA_DLQ_TOPIC"`
+	// число воркеров консьюмера; сообщения одной партиции всегда попадают к одному воркеру
+	Concurrency int `env:"KAFKA_CONCURRENCY" env-default:"1"`
+
+	// ретраи при временных ошибках: попытки внутри процесса, затем retry-топики вида topic=delay, затем DLQ
+	RetryAttempts   int           `env:"KAFKA_RETRY_ATTEMPTS" env-default:"3"`
+	RetryBackoff    time.Duration `env:"KAFKA_RETRY_BACKOFF" env-default:"200ms"`
+	RetryMaxBackoff time.Duration `env:"KAFKA_RETRY_MAX_BACKOFF" env-default:"5s"`
+	RetryTopics     []string      `env:"KAFKA_RETRY_TOPICS" env-separator:"," env-default:"orders_retry_30s=30s,orders_retry_5m=5m"`
+
+	// пакетная запись: до BatchSize сообщений или BatchTimeout с первого сообщения пачки; 0 или 1 - выключено
+	BatchSize    int           `env:"KAFKA_BATCH_SIZE" env-default:"0"`
+	BatchTimeout time.Duration `env:"KAFKA_BATCH_TIMEOUT" env-default:"100ms"`
+}
+
+type OrderConfig struct {
+	// что делать с повторно пришедшим заказом, содержимое которого изменилось: ignore, reject или upsert
+	ConflictPolicy string `env:"ORDER_CONFLICT_POLICY" env-default:"ignore"`
+}
+
+type ValidationConfig struct {
+	// strict - заказ с несходящимися суммами уходит в DLQ, lenient - только предупреждение в логе
+	FinancialMode string `env:"VALIDATION_FINANCIAL_MODE" env-default:"strict"`
+	// допустимое расхождение сумм в минимальных единицах валюты
+	FinancialTolerance int `env:"VALIDATION_FINANCIAL_TOLERANCE" env-default:"0"`
+	// YAML/JSON с профилями правил по entry/delivery_service; пусто - только встроенные правила
+	RulesFile           string        `env:"VALIDATION_RULES_FILE"`
+	RulesReloadInterval time.Duration `env:"VALIDATION_RULES_RELOAD_INTERVAL" env-default:"10s"`
+}
+
+type FeedConfig struct {
+	// сколько заказов может ждать отправки одному клиенту, дальше заказы для него пропускаются
+	Buffer int `env:"FEED_BUFFER" env-default:"64"`
+	// интервал keep-alive для SSE и ping для WebSocket
+	Heartbeat time.Duration `env:"FEED_HEARTBEAT" env-default:"15s"`
+}
+
+type OutboxConfig struct {
+	// топик событий order.saved; пустой - релей не запускается, события в outbox не пишутся
+	Topic        string        `env:"OUTBOX_TOPIC" env-default:"order_events"`
+	PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" env-default:"1s"`
+	BatchSize    int           `env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	// сколько хранить отправленные события; 0 - не удалять
+	Retention time.Duration `env:"OUTBOX_RETENTION" env-default:"168h"`
 }
 
 func MustLoad() *Config {
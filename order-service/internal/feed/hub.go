@@ -0,0 +1,170 @@
+package feed
+
+import (
+	"errors"
+	"log/slog"
+	"order-service/internal/metrics"
+	"order-service/internal/models"
+	"sync"
+	"sync/atomic"
+
+	gojson "github.com/goccy/go-json"
+)
+
+// ErrClosed - хаб остановлен вместе с HTTP-сервером, новые подписки не принимаются
+var ErrClosed = errors.New("feed is closed")
+
+// DefaultBuffer - сколько заказов может ждать отправки одному клиенту
+const DefaultBuffer = 64
+
+// Filter - какие заказы получает подписчик. Пустые поля не участвуют в фильтрации
+type Filter struct {
+	DeliveryService string
+	CustomerID      string
+}
+
+func (f Filter) Match(o *models.Order) bool {
+	return (f.DeliveryService == "" || f.DeliveryService == o.DeliveryService) &&
+		(f.CustomerID == "" || f.CustomerID == o.CustomerID)
+}
+
+// Event - сохранённый заказ в JSON. Заказ сериализуется один раз для всех подписчиков
+type Event struct {
+	OrderUID string
+	Data     []byte
+}
+
+// Hub - раздаёт сохранённые заказы клиентам живой ленты.
+// Publish никогда не ждёт клиентов: у каждого подписчика свой буфер, и если клиент не успевает его вычитывать,
+// заказы для него пропускаются, а число пропущенных отдаётся ему при следующей отправке
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	buffer int
+	log    *slog.Logger
+}
+
+func NewHub(buffer int, log *slog.Logger) *Hub {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Hub{
+		subs:   make(map[*Subscription]struct{}),
+		buffer: buffer,
+		log:    log,
+	}
+}
+
+// Publish - отправляет заказ подходящим подписчикам без ожидания
+func (h *Hub) Publish(o *models.Order) {
+	const op = "feed.Hub.Publish"
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var ev *Event
+	for s := range h.subs {
+		if !s.filter.Match(o) {
+			continue
+		}
+		//сериализуем только если заказ кому-то нужен
+		if ev == nil {
+			data, err := gojson.Marshal(o)
+			if err != nil {
+				h.log.Error("failed to encode order for feed",
+					slog.String("op", op),
+					slog.String("order_uid", o.OrderUID),
+					slog.Any("error", err),
+				)
+				return
+			}
+			ev = &Event{OrderUID: o.OrderUID, Data: data}
+		}
+
+		select {
+		case s.events <- ev:
+		default:
+			s.dropped.Add(1)
+			metrics.FeedEventsDropped.Inc()
+		}
+	}
+}
+
+// Subscribe - новый подписчик ленты. Подписку нужно закрыть через Close
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	s := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan *Event, h.buffer),
+		done:   make(chan struct{}),
+	}
+	h.subs[s] = struct{}{}
+	metrics.FeedSubscribers.Inc()
+	return s, nil
+}
+
+// Close - закрывает все подписки, чтобы стримы завершились и не держали остановку HTTP-сервера
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	subs := make([]*Subscription, 0, len(h.subs))
+	for s := range h.subs {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subs {
+		s.Close()
+	}
+}
+
+// Subscribers - число подключённых клиентов
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Subscription - подписка одного клиента
+type Subscription struct {
+	hub     *Hub
+	filter  Filter
+	events  chan *Event
+	dropped atomic.Uint64
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Events - заказы для отправки клиенту. Канал не закрывается, конец подписки - Done
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Done - закрывается, когда подписка закрыта клиентом или остановкой хаба
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped - сколько заказов пропущено с прошлого вызова из-за переполненного буфера
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Swap(0)
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subs, s)
+		s.hub.mu.Unlock()
+
+		metrics.FeedSubscribers.Dec()
+		close(s.done)
+	})
+}
@@ -0,0 +1,167 @@
+package feed_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/feed"
+	"order-service/internal/models"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func order(uid, deliveryService, customerID string) *models.Order {
+	return &models.Order{OrderUID: uid, DeliveryService: deliveryService, CustomerID: customerID}
+}
+
+func receive(t *testing.T, sub *feed.Subscription) *feed.Event {
+	t.Helper()
+	select {
+	case ev := <-sub.Events():
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return nil
+	}
+}
+
+func assertNoEvent(t *testing.T, sub *feed.Subscription) {
+	t.Helper()
+	select {
+	case ev := <-sub.Events():
+		t.Fatalf("unexpected event %s", ev.OrderUID)
+	default:
+	}
+}
+
+func TestHub_FanOut(t *testing.T) {
+	hub := feed.NewHub(4, testLogger())
+
+	all, err := hub.Subscribe(feed.Filter{})
+	require.NoError(t, err)
+	defer all.Close()
+	meest, err := hub.Subscribe(feed.Filter{DeliveryService: "meest"})
+	require.NoError(t, err)
+	defer meest.Close()
+	customer, err := hub.Subscribe(feed.Filter{DeliveryService: "meest", CustomerID: "c1"})
+	require.NoError(t, err)
+	defer customer.Close()
+
+	hub.Publish(order("uid-1", "meest", "c1"))
+	hub.Publish(order("uid-2", "meest", "c2"))
+	hub.Publish(order("uid-3", "cdek", "c1"))
+
+	for _, uid := range []string{"uid-1", "uid-2", "uid-3"} {
+		assert.Equal(t, uid, receive(t, all).OrderUID)
+	}
+	assert.Equal(t, "uid-1", receive(t, meest).OrderUID)
+	assert.Equal(t, "uid-2", receive(t, meest).OrderUID)
+	assertNoEvent(t, meest)
+
+	ev := receive(t, customer)
+	assertNoEvent(t, customer)
+
+	var got models.Order
+	require.NoError(t, json.Unmarshal(ev.Data, &got))
+	assert.Equal(t, "uid-1", got.OrderUID)
+}
+
+func TestHub_SlowSubscriberDoesNotBlock(t *testing.T) {
+	hub := feed.NewHub(2, testLogger())
+
+	slow, err := hub.Subscribe(feed.Filter{})
+	require.NoError(t, err)
+	defer slow.Close()
+
+	//никто не читает: Publish не ждёт, лишние заказы для клиента пропускаются
+	done := make(chan struct{})
+	go func() {
+		for _, uid := range []string{"uid-1", "uid-2", "uid-3", "uid-4", "uid-5"} {
+			hub.Publish(order(uid, "", ""))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+
+	assert.Equal(t, "uid-1", receive(t, slow).OrderUID)
+	assert.Equal(t, "uid-2", receive(t, slow).OrderUID)
+	assertNoEvent(t, slow)
+	assert.Equal(t, uint64(3), slow.Dropped())
+	assert.Equal(t, uint64(0), slow.Dropped())
+
+	//после того как клиент вычитал буфер, он снова получает заказы
+	hub.Publish(order("uid-6", "", ""))
+	assert.Equal(t, "uid-6", receive(t, slow).OrderUID)
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := feed.NewHub(1, testLogger())
+
+	sub, err := hub.Subscribe(feed.Filter{})
+	require.NoError(t, err)
+	other, err := hub.Subscribe(feed.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, hub.Subscribers())
+
+	sub.Close()
+	sub.Close()
+	assert.Equal(t, 1, hub.Subscribers())
+	hub.Publish(order("uid-1", "", ""))
+	assertNoEvent(t, sub)
+
+	hub.Close()
+	select {
+	case <-other.Done():
+	default:
+		t.Fatal("subscription not closed with the hub")
+	}
+	assert.Equal(t, 0, hub.Subscribers())
+
+	_, err = hub.Subscribe(feed.Filter{})
+	assert.ErrorIs(t, err, feed.ErrClosed)
+	hub.Publish(order("uid-2", "", ""))
+}
+
+func TestHub_ConcurrentPublish(t *testing.T) {
+	hub := feed.NewHub(1000, testLogger())
+
+	sub, err := hub.Subscribe(feed.Filter{})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				hub.Publish(order(string(rune('a'+w))+string(rune(i)), "", ""))
+			}
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := hub.Subscribe(feed.Filter{})
+			if err == nil {
+				s.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, sub.Events(), 400)
+}
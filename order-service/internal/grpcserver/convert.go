@@ -0,0 +1,171 @@
+package grpcserver
+
+import (
+	orderv1 "order-service/api/order/v1"
+	"order-service/internal/models"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var statusToProto = map[models.OrderStatus]orderv1.OrderStatus{
+	models.StatusCreated:   orderv1.OrderStatus_ORDER_STATUS_CREATED,
+	models.StatusPaid:      orderv1.OrderStatus_ORDER_STATUS_PAID,
+	models.StatusAssembled: orderv1.OrderStatus_ORDER_STATUS_ASSEMBLED,
+	models.StatusShipped:   orderv1.OrderStatus_ORDER_STATUS_SHIPPED,
+	models.StatusDelivered: orderv1.OrderStatus_ORDER_STATUS_DELIVERED,
+	models.StatusCancelled: orderv1.OrderStatus_ORDER_STATUS_CANCELLED,
+	models.StatusReturned:  orderv1.OrderStatus_ORDER_STATUS_RETURNED,
+}
+
+var statusFromProto = func() map[orderv1.OrderStatus]models.OrderStatus {
+	m := make(map[orderv1.OrderStatus]models.OrderStatus, len(statusToProto))
+	for s, p := range statusToProto {
+		m[p] = s
+	}
+	return m
+}()
+
+func orderToProto(o *models.Order) *orderv1.Order {
+	items := make([]*orderv1.Item, len(o.Items))
+	for i, it := range o.Items {
+		items[i] = &orderv1.Item{
+			ChrtId:      it.ChrtID,
+			TrackNumber: it.TrackNumber,
+			Price:       int64(it.Price),
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        int64(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int64(it.TotalPrice),
+			NmId:        it.NmID,
+			Brand:       it.Brand,
+			Status:      int64(it.Status),
+		}
+	}
+
+	return &orderv1.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &orderv1.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &orderv1.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDT,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.Shardkey,
+		SmId:              int64(o.SmID),
+		DateCreated:       timeToProto(o.DateCreated),
+		OofShard:          o.OofShard,
+		Status:            statusToProto[o.Status],
+	}
+}
+
+// orderFromProto - отсутствующие delivery и payment дают пустые структуры, их поля отклонит валидатор
+func orderFromProto(o *orderv1.Order) *models.Order {
+	d, p := o.GetDelivery(), o.GetPayment()
+
+	items := make([]models.Item, len(o.GetItems()))
+	for i, it := range o.GetItems() {
+		items[i] = models.Item{
+			ChrtID:      it.GetChrtId(),
+			TrackNumber: it.GetTrackNumber(),
+			Price:       int(it.GetPrice()),
+			Rid:         it.GetRid(),
+			Name:        it.GetName(),
+			Sale:        int(it.GetSale()),
+			Size:        it.GetSize(),
+			TotalPrice:  int(it.GetTotalPrice()),
+			NmID:        it.GetNmId(),
+			Brand:       it.GetBrand(),
+			Status:      int(it.GetStatus()),
+		}
+	}
+
+	return &models.Order{
+		OrderUID:    o.GetOrderUid(),
+		TrackNumber: o.GetTrackNumber(),
+		Entry:       o.GetEntry(),
+		Delivery: models.Delivery{
+			Name:    d.GetName(),
+			Phone:   d.GetPhone(),
+			Zip:     d.GetZip(),
+			City:    d.GetCity(),
+			Address: d.GetAddress(),
+			Region:  d.GetRegion(),
+			Email:   d.GetEmail(),
+		},
+		Payment: models.Payment{
+			Transaction:  p.GetTransaction(),
+			RequestID:    p.GetRequestId(),
+			Currency:     p.GetCurrency(),
+			Provider:     p.GetProvider(),
+			Amount:       int(p.GetAmount()),
+			PaymentDT:    p.GetPaymentDt(),
+			Bank:         p.GetBank(),
+			DeliveryCost: int(p.GetDeliveryCost()),
+			GoodsTotal:   int(p.GetGoodsTotal()),
+			CustomFee:    int(p.GetCustomFee()),
+		},
+		Items:             items,
+		Locale:            o.GetLocale(),
+		InternalSignature: o.GetInternalSignature(),
+		CustomerID:        o.GetCustomerId(),
+		DeliveryService:   o.GetDeliveryService(),
+		Shardkey:          o.GetShardkey(),
+		SmID:              int(o.GetSmId()),
+		DateCreated:       timeFromProto(o.GetDateCreated()),
+		OofShard:          o.GetOofShard(),
+		Status:            statusFromProto[o.GetStatus()],
+	}
+}
+
+func filterFromProto(f *orderv1.OrderFilter) models.OrderFilter {
+	return models.OrderFilter{
+		CustomerID:      f.GetCustomerId(),
+		TrackNumber:     f.GetTrackNumber(),
+		Phone:           f.GetPhone(),
+		Email:           f.GetEmail(),
+		Transaction:     f.GetTransaction(),
+		DeliveryService: f.GetDeliveryService(),
+		CreatedFrom:     timeFromProto(f.GetCreatedFrom()),
+		CreatedTo:       timeFromProto(f.GetCreatedTo()),
+		NmID:            int(f.GetNmId()),
+		Brand:           f.GetBrand(),
+	}
+}
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
@@ -0,0 +1,228 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	orderv1 "order-service/api/order/v1"
+	"order-service/internal/handlers"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/service"
+	"order-service/internal/validator"
+	"runtime/debug"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Server - gRPC API заказов поверх того же сервиса, что и REST API
+type Server struct {
+	orderv1.UnimplementedOrderServiceServer
+
+	service   handlers.OrderService
+	validator *validator.Validator
+	log       *slog.Logger
+}
+
+func NewServer(service handlers.OrderService, log *slog.Logger, orderValidator *validator.Validator) *Server {
+	return &Server{
+		service:   service,
+		validator: orderValidator,
+		log:       log,
+	}
+}
+
+// NewGRPCServer - grpc.Server с зарегистрированным OrderService и reflection для grpcurl.
+// Паника в обработчике возвращается клиенту как INTERNAL и не роняет процесс, как gin.Recovery в REST API
+func NewGRPCServer(srv *Server, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(srv.recoverUnary),
+		grpc.ChainStreamInterceptor(srv.recoverStream),
+	)
+	s := grpc.NewServer(opts...)
+	orderv1.RegisterOrderServiceServer(s, srv)
+	reflection.Register(s)
+	return s
+}
+
+// GetOrder - заказ по order_uid или трек-номеру
+func (s *Server) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	const op = "grpc.GetOrder"
+
+	var (
+		order *models.Order
+		err   error
+	)
+	switch key := req.GetKey().(type) {
+	case *orderv1.GetOrderRequest_OrderUid:
+		if key.OrderUid == "" {
+			return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+		}
+		order, err = s.service.GetOrderByUID(ctx, key.OrderUid)
+	case *orderv1.GetOrderRequest_TrackNumber:
+		if key.TrackNumber == "" {
+			return nil, status.Error(codes.InvalidArgument, "track_number is required")
+		}
+		order, err = s.service.GetOrderByTrackNumber(ctx, key.TrackNumber)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "order_uid or track_number is required")
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "order not found")
+		}
+		return nil, s.internal(op, err)
+	}
+
+	return orderToProto(order), nil
+}
+
+// ListOrders - страница заказов по фильтру
+func (s *Server) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	const op = "grpc.ListOrders"
+
+	filter := filterFromProto(req.GetFilter())
+
+	var verrs validator.ValidationErrors
+	if req.GetPageSize() < 0 {
+		verrs = append(verrs, validator.FieldError{Field: "page_size", Code: validator.CodeMustBeNonNegative, Message: "must be >= 0"})
+	}
+	filter.Limit = int(req.GetPageSize())
+	if token := req.GetPageToken(); token != "" {
+		after, err := models.DecodeCursor(token)
+		if err != nil {
+			verrs = append(verrs, validator.FieldError{Field: "page_token", Code: validator.CodeInvalidValue, Message: "is invalid"})
+		}
+		filter.After = after
+	}
+	if len(verrs) > 0 {
+		return nil, invalidArgument(verrs)
+	}
+
+	page, err := s.service.SearchOrders(ctx, filter)
+	if err != nil {
+		return nil, s.internal(op, err)
+	}
+
+	resp := &orderv1.ListOrdersResponse{
+		Orders:        make([]*orderv1.Order, len(page.Orders)),
+		NextPageToken: page.NextCursor,
+	}
+	for i, o := range page.Orders {
+		resp.Orders[i] = orderToProto(o)
+	}
+	return resp, nil
+}
+
+// CreateOrder - проверяет заказ тем же валидатором, что и консьюмер, и сохраняет его
+func (s *Server) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.Order, error) {
+	const op = "grpc.CreateOrder"
+
+	if req.GetOrder() == nil {
+		return nil, status.Error(codes.InvalidArgument, "order is required")
+	}
+	order := orderFromProto(req.GetOrder())
+
+	if err := s.validator.Validate(s.log, order); err != nil {
+		var verrs validator.ValidationErrors
+		if errors.As(err, &verrs) {
+			return nil, invalidArgument(verrs)
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err := s.service.CreateOrder(ctx, order)
+	switch {
+	case err == nil:
+		return orderToProto(order), nil
+	case errors.Is(err, repository.ErrOrderChanged):
+		return nil, status.Error(codes.AlreadyExists, repository.ErrOrderChanged.Error())
+	case errors.Is(err, repository.ErrOrderExists):
+		return nil, status.Error(codes.AlreadyExists, repository.ErrOrderExists.Error())
+	default:
+		return nil, s.internal(op, err)
+	}
+}
+
+// StreamOrders - отдаёт все заказы по фильтру, сам проходя по страницам поиска
+func (s *Server) StreamOrders(req *orderv1.StreamOrdersRequest, stream grpc.ServerStreamingServer[orderv1.Order]) error {
+	const op = "grpc.StreamOrders"
+
+	ctx := stream.Context()
+	filter := filterFromProto(req.GetFilter())
+	filter.Limit = service.MaxSearchLimit
+
+	for {
+		page, err := s.service.SearchOrders(ctx, filter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			return s.internal(op, err)
+		}
+
+		for _, o := range page.Orders {
+			if err = stream.Send(orderToProto(o)); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		if filter.After, err = models.DecodeCursor(page.NextCursor); err != nil {
+			return s.internal(op, err)
+		}
+	}
+}
+
+// invalidArgument - INVALID_ARGUMENT с ошибками по полям в google.rpc.BadRequest, код ошибки - в reason
+func invalidArgument(verrs validator.ValidationErrors) error {
+	st := status.New(codes.InvalidArgument, verrs.Error())
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(verrs))
+	for i, fe := range verrs {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       fe.Field,
+			Description: fe.Message,
+			Reason:      fe.Code,
+		}
+	}
+
+	withDetails, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+func (s *Server) internal(op string, err error) error {
+	s.log.Error("grpc request failed",
+		slog.String("op", op),
+		slog.Any("error", err),
+	)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func (s *Server) recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.internal(info.FullMethod, fmt.Errorf("panic: %v\n%s", r, debug.Stack()))
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func (s *Server) recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.internal(info.FullMethod, fmt.Errorf("panic: %v\n%s", r, debug.Stack()))
+		}
+	}()
+	return handler(srv, ss)
+}
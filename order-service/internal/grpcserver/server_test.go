@@ -0,0 +1,332 @@
+package grpcserver_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	orderv1 "order-service/api/order/v1"
+	"order-service/internal/grpcserver"
+	"order-service/internal/handlers/mocks"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/service"
+	"order-service/internal/validator"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// setupClient - сервер на bufconn и клиент к нему, оба закрываются в t.Cleanup
+func setupClient(t *testing.T) (orderv1.OrderServiceClient, *mocks.OrderService) {
+	t.Helper()
+	svc := mocks.NewOrderService(t)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcserver.NewGRPCServer(grpcserver.NewServer(svc, testLogger(), validator.New(validator.DefaultOptions)))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return orderv1.NewOrderServiceClient(conn), svc
+}
+
+func validOrder(uid string) *models.Order {
+	return &models.Order{
+		OrderUID:    uid,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  uid,
+			RequestID:    "req-1",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDT:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{
+			{
+				ChrtID:      9934930,
+				TrackNumber: "WBILMTESTTRACK",
+				Price:       453,
+				Rid:         "ab4219087a764ae0btest",
+				Name:        "Mascaras",
+				Sale:        30,
+				Size:        "0",
+				TotalPrice:  317,
+				NmID:        2389212,
+				Brand:       "Vivienne Sabo",
+				Status:      202,
+			},
+		},
+		Locale:            "en",
+		InternalSignature: "sig",
+		CustomerID:        "test",
+		DeliveryService:   "meest",
+		Shardkey:          "9",
+		SmID:              99,
+		DateCreated:       time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:          "1",
+		Status:            models.StatusPaid,
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	client, svc := setupClient(t)
+
+	svc.On("GetOrderByUID", mock.Anything, "uid-1").Return(validOrder("uid-1"), nil).Once()
+	svc.On("GetOrderByTrackNumber", mock.Anything, "WBILMTESTTRACK").Return(validOrder("uid-2"), nil).Once()
+
+	got, err := client.GetOrder(context.Background(), &orderv1.GetOrderRequest{
+		Key: &orderv1.GetOrderRequest_OrderUid{OrderUid: "uid-1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "uid-1", got.GetOrderUid())
+	assert.Equal(t, orderv1.OrderStatus_ORDER_STATUS_PAID, got.GetStatus())
+	assert.Equal(t, int64(1817), got.GetPayment().GetAmount())
+	assert.Equal(t, "Vivienne Sabo", got.GetItems()[0].GetBrand())
+	assert.True(t, got.GetDateCreated().AsTime().Equal(validOrder("").DateCreated))
+
+	got, err = client.GetOrder(context.Background(), &orderv1.GetOrderRequest{
+		Key: &orderv1.GetOrderRequest_TrackNumber{TrackNumber: "WBILMTESTTRACK"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "uid-2", got.GetOrderUid())
+}
+
+func TestGetOrder_Errors(t *testing.T) {
+	client, svc := setupClient(t)
+
+	svc.On("GetOrderByUID", mock.Anything, "missing").Return(nil, repository.ErrNotFound).Once()
+	svc.On("GetOrderByUID", mock.Anything, "broken").Return(nil, errors.New("db down")).Once()
+
+	tests := []struct {
+		name string
+		req  *orderv1.GetOrderRequest
+		code codes.Code
+	}{
+		{name: "not found", req: &orderv1.GetOrderRequest{Key: &orderv1.GetOrderRequest_OrderUid{OrderUid: "missing"}}, code: codes.NotFound},
+		{name: "service error", req: &orderv1.GetOrderRequest{Key: &orderv1.GetOrderRequest_OrderUid{OrderUid: "broken"}}, code: codes.Internal},
+		{name: "no key", req: &orderv1.GetOrderRequest{}, code: codes.InvalidArgument},
+		{name: "empty uid", req: &orderv1.GetOrderRequest{Key: &orderv1.GetOrderRequest_OrderUid{}}, code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetOrder(context.Background(), tt.req)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	client, svc := setupClient(t)
+
+	after := models.Cursor{DateCreated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), OrderUID: "uid-0"}
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	svc.On("SearchOrders", mock.Anything, models.OrderFilter{
+		CustomerID:  "test",
+		NmID:        2389212,
+		CreatedFrom: from,
+		Limit:       2,
+		After:       &after,
+	}).Return(&models.OrderPage{
+		Orders:     []*models.Order{validOrder("uid-1"), validOrder("uid-2")},
+		NextCursor: "next",
+	}, nil).Once()
+
+	resp, err := client.ListOrders(context.Background(), &orderv1.ListOrdersRequest{
+		Filter: &orderv1.OrderFilter{
+			CustomerId:  "test",
+			NmId:        2389212,
+			CreatedFrom: timestamppb.New(from),
+		},
+		PageSize:  2,
+		PageToken: after.Encode(),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetOrders(), 2)
+	assert.Equal(t, "uid-2", resp.GetOrders()[1].GetOrderUid())
+	assert.Equal(t, "next", resp.GetNextPageToken())
+}
+
+func TestListOrders_InvalidArgument(t *testing.T) {
+	client, svc := setupClient(t)
+
+	_, err := client.ListOrders(context.Background(), &orderv1.ListOrdersRequest{PageSize: -1, PageToken: "%%%"})
+
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	violations := fieldViolations(t, err)
+	require.Len(t, violations, 2)
+	assert.Equal(t, "page_size", violations[0].GetField())
+	assert.Equal(t, "page_token", violations[1].GetField())
+	assert.Equal(t, validator.CodeInvalidValue, violations[1].GetReason())
+	svc.AssertNotCalled(t, "SearchOrders", mock.Anything, mock.Anything)
+}
+
+func TestCreateOrder(t *testing.T) {
+	client, svc := setupClient(t)
+
+	//заказ, полученный через GetOrder и отправленный обратно, доходит до сервиса без потерь
+	want := validOrder("uid-1")
+	svc.On("GetOrderByUID", mock.Anything, "uid-1").Return(validOrder("uid-1"), nil).Once()
+	svc.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool {
+		return assert.ObjectsAreEqual(want, o)
+	})).Return(nil).Once()
+
+	order, err := client.GetOrder(context.Background(), &orderv1.GetOrderRequest{
+		Key: &orderv1.GetOrderRequest_OrderUid{OrderUid: "uid-1"},
+	})
+	require.NoError(t, err)
+
+	got, err := client.CreateOrder(context.Background(), &orderv1.CreateOrderRequest{Order: order})
+	require.NoError(t, err)
+	assert.Equal(t, "uid-1", got.GetOrderUid())
+}
+
+func TestCreateOrder_Invalid(t *testing.T) {
+	client, svc := setupClient(t)
+
+	order := &orderv1.Order{OrderUid: "uid-1", TrackNumber: "TRACK"}
+	_, err := client.CreateOrder(context.Background(), &orderv1.CreateOrderRequest{Order: order})
+
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	violations := fieldViolations(t, err)
+	require.NotEmpty(t, violations)
+	assert.Equal(t, "entry", violations[0].GetField())
+	assert.Equal(t, validator.CodeRequired, violations[0].GetReason())
+	assert.Equal(t, "is required", violations[0].GetDescription())
+
+	_, err = client.CreateOrder(context.Background(), &orderv1.CreateOrderRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	svc.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+}
+
+func TestCreateOrder_AlreadyExists(t *testing.T) {
+	client, svc := setupClient(t)
+
+	svc.On("GetOrderByUID", mock.Anything, "uid-1").Return(validOrder("uid-1"), nil).Once()
+	svc.On("CreateOrder", mock.Anything, mock.Anything).Return(repository.ErrOrderChanged).Once()
+
+	order, err := client.GetOrder(context.Background(), &orderv1.GetOrderRequest{
+		Key: &orderv1.GetOrderRequest_OrderUid{OrderUid: "uid-1"},
+	})
+	require.NoError(t, err)
+
+	_, err = client.CreateOrder(context.Background(), &orderv1.CreateOrderRequest{Order: order})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Equal(t, repository.ErrOrderChanged.Error(), status.Convert(err).Message())
+}
+
+func TestStreamOrders(t *testing.T) {
+	client, svc := setupClient(t)
+
+	cursor := models.Cursor{DateCreated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), OrderUID: "uid-2"}
+
+	svc.On("SearchOrders", mock.Anything, models.OrderFilter{
+		DeliveryService: "meest",
+		Limit:           service.MaxSearchLimit,
+	}).Return(&models.OrderPage{
+		Orders:     []*models.Order{validOrder("uid-1"), validOrder("uid-2")},
+		NextCursor: cursor.Encode(),
+	}, nil).Once()
+	svc.On("SearchOrders", mock.Anything, models.OrderFilter{
+		DeliveryService: "meest",
+		Limit:           service.MaxSearchLimit,
+		After:           &cursor,
+	}).Return(&models.OrderPage{
+		Orders: []*models.Order{validOrder("uid-3")},
+	}, nil).Once()
+
+	stream, err := client.StreamOrders(context.Background(), &orderv1.StreamOrdersRequest{
+		Filter: &orderv1.OrderFilter{DeliveryService: "meest"},
+	})
+	require.NoError(t, err)
+
+	var uids []string
+	for {
+		order, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		uids = append(uids, order.GetOrderUid())
+	}
+	assert.Equal(t, []string{"uid-1", "uid-2", "uid-3"}, uids)
+}
+
+func TestStreamOrders_ServiceError(t *testing.T) {
+	client, svc := setupClient(t)
+
+	svc.On("SearchOrders", mock.Anything, mock.Anything).Return(nil, errors.New("db down")).Once()
+
+	stream, err := client.StreamOrders(context.Background(), &orderv1.StreamOrdersRequest{})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestRecoverPanic(t *testing.T) {
+	client, svc := setupClient(t)
+
+	svc.On("GetOrderByUID", mock.Anything, "uid-1").Run(func(mock.Arguments) {
+		panic("boom")
+	}).Return(nil, nil).Once()
+
+	_, err := client.GetOrder(context.Background(), &orderv1.GetOrderRequest{
+		Key: &orderv1.GetOrderRequest_OrderUid{OrderUid: "uid-1"},
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func fieldViolations(t *testing.T, err error) []*errdetails.BadRequest_FieldViolation {
+	t.Helper()
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			return br.GetFieldViolations()
+		}
+	}
+	t.Fatalf("no BadRequest details in %v", err)
+	return nil
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	gojson "github.com/goccy/go-json"
+)
+
+const (
+	// MaxBatchOrders - максимальное число заказов в POST /orders:batch
+	MaxBatchOrders = 1000
+
+	maxOrderBodyBytes = 1 << 20
+	maxBatchBodyBytes = 32 << 20
+)
+
+// OrderResult - результат сохранения одного заказа из POST /orders:batch.
+// Status - HTTP-статус, который вернул бы POST /order для этого заказа
+type OrderResult struct {
+	OrderUID string                     `json:"order_uid,omitempty"`
+	Status   int                        `json:"status"`
+	Error    string                     `json:"error,omitempty"`
+	Errors   validator.ValidationErrors `json:"errors,omitempty"`
+}
+
+// BatchResult - ответ POST /orders:batch, результаты в порядке заказов в запросе
+type BatchResult struct {
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	Results []OrderResult `json:"results"`
+}
+
+// CreateOrder - обработчик для POST /order. Принимает тот же JSON, что и топик orders:
+// 201 - заказ сохранён, 409 - заказ с таким order_uid уже есть, 422 - заказ не прошёл валидацию
+func (h *Handler) CreateOrder(c *gin.Context) {
+	body, err := readBody(c, maxOrderBodyBytes)
+	if err != nil {
+		respondBodyError(c, err)
+		return
+	}
+
+	var order models.Order
+	if err = gojson.Unmarshal(body, &order); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json: " + err.Error()})
+		return
+	}
+
+	res := h.createOrder(c, &order)
+	switch {
+	case len(res.Errors) > 0:
+		c.JSON(res.Status, gin.H{"error": res.Error, "errors": res.Errors})
+		return
+	case res.Status != http.StatusCreated:
+		c.JSON(res.Status, gin.H{"error": res.Error})
+		return
+	}
+	c.JSON(http.StatusCreated, &order)
+}
+
+// CreateOrders - обработчик для POST /orders:batch. Принимает JSON-массив заказов и сохраняет каждый
+// независимо от остальных: ошибка в одном заказе не отменяет сохранение других
+func (h *Handler) CreateOrders(c *gin.Context) {
+	body, err := readBody(c, maxBatchBodyBytes)
+	if err != nil {
+		respondBodyError(c, err)
+		return
+	}
+
+	//элементы разбираются по отдельности, чтобы заказ с неверными типами полей не ронял весь батч
+	var raw []gojson.RawMessage
+	if err = gojson.Unmarshal(body, &raw); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json: " + err.Error()})
+		return
+	}
+	if len(raw) == 0 || len(raw) > MaxBatchOrders {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "batch must contain from 1 to 1000 orders"})
+		return
+	}
+
+	result := BatchResult{Results: make([]OrderResult, len(raw))}
+	for i, item := range raw {
+		var order models.Order
+		if err = gojson.Unmarshal(item, &order); err != nil {
+			result.Results[i] = OrderResult{Status: http.StatusBadRequest, Error: "invalid json: " + err.Error()}
+		} else {
+			result.Results[i] = h.createOrder(c, &order)
+		}
+
+		if result.Results[i].Status == http.StatusCreated {
+			result.Created++
+		} else {
+			result.Failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// createOrder - валидирует и сохраняет заказ, возвращает результат в виде HTTP-статуса
+func (h *Handler) createOrder(c *gin.Context, order *models.Order) OrderResult {
+	const op = "handler.createOrder"
+
+	res := OrderResult{OrderUID: order.OrderUID}
+
+	if err := h.validator.Validate(h.log, order); err != nil {
+		res.Status, res.Error = http.StatusUnprocessableEntity, validator.ErrBadMessage.Error()
+		errors.As(err, &res.Errors)
+		return res
+	}
+
+	err := h.service.CreateOrder(c.Request.Context(), order)
+	switch {
+	case err == nil:
+		res.Status = http.StatusCreated
+	case errors.Is(err, repository.ErrOrderChanged):
+		res.Status, res.Error = http.StatusConflict, repository.ErrOrderChanged.Error()
+	case errors.Is(err, repository.ErrOrderExists):
+		res.Status, res.Error = http.StatusConflict, repository.ErrOrderExists.Error()
+	default:
+		h.log.Error("failed to create order",
+			slog.String("op", op),
+			slog.String("order_uid", order.OrderUID),
+			slog.Any("error", err),
+		)
+		res.Status, res.Error = http.StatusInternalServerError, "Internal server error"
+	}
+	return res
+}
+
+func readBody(c *gin.Context, limit int64) ([]byte, error) {
+	return io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+}
+
+func respondBodyError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+}
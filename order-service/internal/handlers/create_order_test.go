@@ -0,0 +1,233 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/handlers"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/validator"
+)
+
+func validOrder(uid string) *models.Order {
+	return &models.Order{
+		OrderUID:    uid,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  uid,
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDT:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{
+			{
+				ChrtID:      9934930,
+				TrackNumber: "WBILMTESTTRACK",
+				Price:       453,
+				Rid:         "ab4219087a764ae0btest",
+				Name:        "Mascaras",
+				Sale:        30,
+				TotalPrice:  317,
+				NmID:        2389212,
+				Status:      202,
+			},
+		},
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+	}
+}
+
+func postJSON(t *testing.T, path string, v any) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(v)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestCreateOrder_Created(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	svc.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool {
+		return o.OrderUID == "uid-1"
+	})).Return(nil).Once()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, postJSON(t, "/order", validOrder("uid-1")))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var got models.Order
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "uid-1", got.OrderUID)
+}
+
+func TestCreateOrder_Invalid(t *testing.T) {
+	r, svc := setupRouter(t)
+
+	order := validOrder("uid-1")
+	order.Delivery.Email = "not-an-email"
+	order.Payment.Amount = 1
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, postJSON(t, "/order", order))
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	svc.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+
+	var body struct {
+		Error  string                     `json:"error"`
+		Errors validator.ValidationErrors `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "bad_message", body.Error)
+	require.Len(t, body.Errors, 2)
+	assert.Equal(t, "delivery.email", body.Errors[0].Field)
+	assert.Equal(t, validator.CodeInvalidFormat, body.Errors[0].Code)
+	assert.Equal(t, "payment.amount", body.Errors[1].Field)
+	assert.Equal(t, validator.CodeInconsistentTotal, body.Errors[1].Code)
+}
+
+func TestCreateOrder_Conflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{name: "same content", err: repository.ErrOrderExists, wantErr: repository.ErrOrderExists.Error()},
+		{name: "different content", err: repository.ErrOrderChanged, wantErr: repository.ErrOrderChanged.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, svc := setupRouter(t)
+			defer svc.AssertExpectations(t)
+
+			svc.On("CreateOrder", mock.Anything, mock.Anything).
+				Return(fmt.Errorf("OrderService.CreateOrder: %w", tt.err)).Once()
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, postJSON(t, "/order", validOrder("uid-1")))
+
+			require.Equal(t, http.StatusConflict, rec.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, tt.wantErr), rec.Body.String())
+		})
+	}
+}
+
+func TestCreateOrder_BadRequest(t *testing.T) {
+	r, svc := setupRouter(t)
+
+	for _, body := range []string{"", "{", "[]", `{"order_uid": 1}`} {
+		req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	svc.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+}
+
+func TestCreateOrder_ServiceError(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	svc.On("CreateOrder", mock.Anything, mock.Anything).Return(errors.New("db down")).Once()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, postJSON(t, "/order", validOrder("uid-1")))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestCreateOrders_Batch(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	invalid := validOrder("uid-invalid")
+	invalid.Items[0].NmID = 0
+
+	svc.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool {
+		return o.OrderUID == "uid-new"
+	})).Return(nil).Once()
+	svc.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool {
+		return o.OrderUID == "uid-dup"
+	})).Return(repository.ErrOrderExists).Once()
+
+	batch := []any{validOrder("uid-new"), invalid, validOrder("uid-dup"), "not an order"}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, postJSON(t, "/orders/batch", batch))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got handlers.BatchResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, 1, got.Created)
+	assert.Equal(t, 3, got.Failed)
+	require.Len(t, got.Results, 4)
+
+	assert.Equal(t, handlers.OrderResult{OrderUID: "uid-new", Status: http.StatusCreated}, got.Results[0])
+
+	assert.Equal(t, "uid-invalid", got.Results[1].OrderUID)
+	assert.Equal(t, http.StatusUnprocessableEntity, got.Results[1].Status)
+	require.Len(t, got.Results[1].Errors, 1)
+	assert.Equal(t, "items[0].nm_id", got.Results[1].Errors[0].Field)
+
+	assert.Equal(t, handlers.OrderResult{
+		OrderUID: "uid-dup",
+		Status:   http.StatusConflict,
+		Error:    repository.ErrOrderExists.Error(),
+	}, got.Results[2])
+
+	assert.Equal(t, http.StatusBadRequest, got.Results[3].Status)
+	assert.Empty(t, got.Results[3].OrderUID)
+}
+
+func TestCreateOrders_BadRequest(t *testing.T) {
+	r, svc := setupRouter(t)
+
+	tooMany := make([]*models.Order, handlers.MaxBatchOrders+1)
+	for i := range tooMany {
+		tooMany[i] = validOrder(fmt.Sprintf("uid-%d", i))
+	}
+
+	for name, batch := range map[string]any{
+		"not an array": validOrder("uid-1"),
+		"empty":        []any{},
+		"too many":     tooMany,
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, postJSON(t, "/orders/batch", batch))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+	}
+	svc.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"order-service/internal/feed"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// DefaultFeedHeartbeat - интервал keep-alive ленты, если он не задан
+const DefaultFeedHeartbeat = 15 * time.Second
+
+// FeedHandler - живая лента сохранённых заказов по SSE и WebSocket
+type FeedHandler struct {
+	hub *feed.Hub
+	log *slog.Logger
+	//как часто слать keep-alive, чтобы прокси не закрывали соединение и мёртвые клиенты отваливались
+	heartbeat time.Duration
+	//сколько ждать записи одного сообщения клиенту; клиент, который не читает дольше, отключается.
+	//0 - без дедлайна, как и у сервера без HTTP_TIMEOUT
+	writeTimeout time.Duration
+	upgrader     websocket.Upgrader
+}
+
+func NewFeedHandler(hub *feed.Hub, log *slog.Logger, heartbeat, writeTimeout time.Duration) *FeedHandler {
+	if heartbeat <= 0 {
+		heartbeat = DefaultFeedHeartbeat
+	}
+	return &FeedHandler{
+		hub:          hub,
+		log:          log,
+		heartbeat:    heartbeat,
+		writeTimeout: writeTimeout,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+		},
+	}
+}
+
+// StreamSSE - обработчик для GET /orders/stream. Фильтры: delivery_service, customer_id.
+// События: order - сохранённый заказ, lagged - сколько заказов пропущено, потому что клиент не успевал читать
+func (h *FeedHandler) StreamSSE(c *gin.Context) {
+	const op = "handler.StreamSSE"
+
+	sub, err := h.hub.Subscribe(feedFilter(c))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	//WriteTimeout сервера считается от начала запроса, поэтому стрим сам ставит дедлайн на каждую запись
+	rc := http.NewResponseController(c.Writer)
+	write := func(data []byte) error {
+		if err := rc.SetWriteDeadline(h.writeDeadline()); err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write(data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err = write([]byte(": connected\n\n")); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case ev := <-sub.Events():
+			if n := sub.Dropped(); n > 0 {
+				fmt.Fprintf(&buf, "event: lagged\ndata: {\"dropped\":%d}\n\n", n)
+			}
+			fmt.Fprintf(&buf, "id: %s\nevent: order\ndata: %s\n\n", ev.OrderUID, ev.Data)
+		case <-ticker.C:
+			if n := sub.Dropped(); n > 0 {
+				fmt.Fprintf(&buf, "event: lagged\ndata: {\"dropped\":%d}\n\n", n)
+			}
+			buf.WriteString(": ping\n\n")
+		}
+
+		if err = write(buf.Bytes()); err != nil {
+			h.log.Debug("feed client disconnected",
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+			return
+		}
+	}
+}
+
+// StreamWS - обработчик для GET /orders/stream/ws, WebSocket-вариант StreamSSE с теми же фильтрами.
+// Сообщения: {"type":"order","order":{...}} и {"type":"lagged","dropped":N}
+func (h *FeedHandler) StreamWS(c *gin.Context) {
+	const op = "handler.StreamWS"
+
+	sub, err := h.hub.Subscribe(feedFilter(c))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	//Upgrade сам отвечает клиенту при ошибке
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	//клиент ничего не присылает, но читать нужно, чтобы обрабатывать pong и close
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		conn.SetReadLimit(512)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		select {
+		case <-clientGone:
+			return
+		case <-sub.Done():
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				h.writeDeadline())
+			return
+		case ev := <-sub.Events():
+			if err = h.writeLagged(conn, sub); err == nil {
+				fmt.Fprintf(&buf, `{"type":"order","order":%s}`, ev.Data)
+				err = h.writeWS(conn, websocket.TextMessage, buf.Bytes())
+			}
+		case <-ticker.C:
+			if err = h.writeLagged(conn, sub); err == nil {
+				err = h.writeWS(conn, websocket.PingMessage, nil)
+			}
+		}
+
+		if err != nil {
+			if !errors.Is(err, websocket.ErrCloseSent) {
+				h.log.Debug("feed client disconnected",
+					slog.String("op", op),
+					slog.Any("error", err),
+				)
+			}
+			return
+		}
+	}
+}
+
+func (h *FeedHandler) writeLagged(conn *websocket.Conn, sub *feed.Subscription) error {
+	n := sub.Dropped()
+	if n == 0 {
+		return nil
+	}
+	return h.writeWS(conn, websocket.TextMessage, fmt.Appendf(nil, `{"type":"lagged","dropped":%d}`, n))
+}
+
+func (h *FeedHandler) writeWS(conn *websocket.Conn, messageType int, data []byte) error {
+	if err := conn.SetWriteDeadline(h.writeDeadline()); err != nil {
+		return err
+	}
+	return conn.WriteMessage(messageType, data)
+}
+
+// writeDeadline - дедлайн записи одного сообщения; нулевое время снимает дедлайн
+func (h *FeedHandler) writeDeadline() time.Time {
+	if h.writeTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(h.writeTimeout)
+}
+
+func feedFilter(c *gin.Context) feed.Filter {
+	return feed.Filter{
+		DeliveryService: c.Query("delivery_service"),
+		CustomerID:      c.Query("customer_id"),
+	}
+}
@@ -0,0 +1,230 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/feed"
+	"order-service/internal/handlers"
+	"order-service/internal/models"
+)
+
+// setupFeedServer - настоящий HTTP-сервер с коротким WriteTimeout, как в проде: стрим должен его переживать
+func setupFeedServer(t *testing.T) (*httptest.Server, *feed.Hub) {
+	return setupFeedServerWithTimeouts(t, 100*time.Millisecond, time.Second)
+}
+
+// setupFeedServerWithTimeouts - serverTimeout - WriteTimeout сервера, writeTimeout - дедлайн записи в ленте
+func setupFeedServerWithTimeouts(t *testing.T, serverTimeout, writeTimeout time.Duration) (*httptest.Server, *feed.Hub) {
+	t.Helper()
+	hub := feed.NewHub(16, testLogger())
+	h := handlers.NewFeedHandler(hub, testLogger(), 50*time.Millisecond, writeTimeout)
+
+	r := gin.New()
+	r.GET("/orders/stream", h.StreamSSE)
+	r.GET("/orders/stream/ws", h.StreamWS)
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = serverTimeout
+	srv.Config.RegisterOnShutdown(hub.Close)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv, hub
+}
+
+// waitSubscribers - клиент подписывается асинхронно, публиковать можно после подписки
+func waitSubscribers(t *testing.T, hub *feed.Hub, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return hub.Subscribers() == n }, time.Second, time.Millisecond)
+}
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// nextSSEEvent - следующее событие потока, комментарии (keep-alive) пропускаются
+func nextSSEEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && ev.event != "":
+			return ev
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	srv, hub := setupFeedServer(t)
+
+	resp, err := http.Get(srv.URL + "/orders/stream?delivery_service=meest")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	waitSubscribers(t, hub, 1)
+
+	//дольше WriteTimeout сервера: соединение не должно оборваться
+	time.Sleep(200 * time.Millisecond)
+
+	hub.Publish(&models.Order{OrderUID: "uid-skip", DeliveryService: "cdek"})
+	hub.Publish(&models.Order{OrderUID: "uid-1", DeliveryService: "meest"})
+
+	ev := nextSSEEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "order", ev.event)
+	assert.Equal(t, "uid-1", ev.id)
+
+	var got models.Order
+	require.NoError(t, json.Unmarshal([]byte(ev.data), &got))
+	assert.Equal(t, "uid-1", got.OrderUID)
+}
+
+func TestStreamSSE_NoWriteTimeout(t *testing.T) {
+	//HTTP_TIMEOUT не задан: ни у сервера, ни у ленты нет дедлайна записи
+	srv, hub := setupFeedServerWithTimeouts(t, 0, 0)
+
+	resp, err := http.Get(srv.URL + "/orders/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	waitSubscribers(t, hub, 1)
+
+	//несколько keep-alive: каждая запись должна проходить
+	time.Sleep(200 * time.Millisecond)
+	hub.Publish(&models.Order{OrderUID: "uid-1"})
+
+	ev := nextSSEEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "order", ev.event)
+	assert.Equal(t, "uid-1", ev.id)
+	assert.Equal(t, 1, hub.Subscribers())
+}
+
+func TestStreamSSE_DefaultHeartbeat(t *testing.T) {
+	//FEED_HEARTBEAT=0: вместо паники в time.NewTicker используется интервал по умолчанию
+	hub := feed.NewHub(16, testLogger())
+	h := handlers.NewFeedHandler(hub, testLogger(), 0, time.Second)
+	r := gin.New()
+	r.GET("/orders/stream", h.StreamSSE)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/orders/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	waitSubscribers(t, hub, 1)
+
+	hub.Publish(&models.Order{OrderUID: "uid-1"})
+	ev := nextSSEEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "uid-1", ev.id)
+}
+
+func TestStreamSSE_ClientDisconnect(t *testing.T) {
+	srv, hub := setupFeedServer(t)
+
+	resp, err := http.Get(srv.URL + "/orders/stream")
+	require.NoError(t, err)
+	waitSubscribers(t, hub, 1)
+
+	require.NoError(t, resp.Body.Close())
+	waitSubscribers(t, hub, 0)
+}
+
+func TestStreamSSE_Shutdown(t *testing.T) {
+	srv, hub := setupFeedServer(t)
+
+	resp, err := http.Get(srv.URL + "/orders/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	waitSubscribers(t, hub, 1)
+
+	hub.Close()
+
+	//поток завершается, остановка сервера не ждёт клиентов ленты
+	done := make(chan struct{})
+	go func() {
+		_, _ = bufio.NewReader(resp.Body).ReadString(0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream was not closed")
+	}
+
+	resp2, err := http.Get(srv.URL + "/orders/stream")
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp2.StatusCode)
+}
+
+func TestStreamWS(t *testing.T) {
+	srv, hub := setupFeedServer(t)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/orders/stream/ws?customer_id=c1"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	waitSubscribers(t, hub, 1)
+
+	//клиент читает постоянно: так он отвечает на ping, иначе сервер сочтёт его отвалившимся
+	messages := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			messages <- data
+		}
+	}()
+
+	//дольше WriteTimeout сервера и нескольких ping
+	time.Sleep(200 * time.Millisecond)
+
+	hub.Publish(&models.Order{OrderUID: "uid-skip", CustomerID: "c2"})
+	hub.Publish(&models.Order{OrderUID: "uid-1", CustomerID: "c1"})
+
+	var msg struct {
+		Type  string       `json:"type"`
+		Order models.Order `json:"order"`
+	}
+	select {
+	case data := <-messages:
+		require.NoError(t, json.Unmarshal(data, &msg))
+	case err = <-readErr:
+		t.Fatalf("read: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+	}
+	assert.Equal(t, "order", msg.Type)
+	assert.Equal(t, "uid-1", msg.Order.OrderUID)
+
+	hub.Close()
+	select {
+	case err = <-readErr:
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	case <-time.After(time.Second):
+		t.Fatal("connection was not closed")
+	}
+}
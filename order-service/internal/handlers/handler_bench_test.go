@@ -14,6 +14,7 @@ import (
 	"order-service/internal/handlers"
 	"order-service/internal/handlers/mocks"
 	"order-service/internal/models"
+	"order-service/internal/validator"
 )
 
 func benchLogger() *slog.Logger {
@@ -76,7 +77,7 @@ func BenchmarkGetOrderByUID_CacheHit(b *testing.B) {
 	order := benchOrder()
 	mockSvc.On("GetOrderByUID", mock.Anything, "bench-uid").Return(order, nil)
 
-	h := handlers.NewHandler(mockSvc, benchLogger())
+	h := handlers.NewHandler(mockSvc, benchLogger(), validator.New(validator.DefaultOptions))
 	r := gin.New()
 	r.GET("/order/:order_uid", h.GetOrderByUID)
 
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"order-service/internal/kafka"
+	"order-service/internal/models"
+	"order-service/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	gojson "github.com/goccy/go-json"
+)
+
+type OrderPublisher interface {
+	SendMessageWithDelivery(ctx context.Context, topic string, key, value []byte, headers map[string]string) (kafka.Delivery, error)
+}
+
+// IngestResult - ответ POST /ingest/orders: куда записан заказ
+type IngestResult struct {
+	OrderUID  string `json:"order_uid"`
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// IngestHandler - шлюз HTTP -> Kafka: заказ проверяется и публикуется в топик заказов,
+// в Postgres его записывает консьюмер, как и заказы от остальных продюсеров
+type IngestHandler struct {
+	publisher OrderPublisher
+	topic     string
+	validator *validator.Validator
+	log       *slog.Logger
+}
+
+func NewIngestHandler(publisher OrderPublisher, topic string, log *slog.Logger, orderValidator *validator.Validator) *IngestHandler {
+	return &IngestHandler{
+		publisher: publisher,
+		topic:     topic,
+		validator: orderValidator,
+		log:       log,
+	}
+}
+
+// IngestOrder - обработчик для POST /ingest/orders. 202 - заказ записан в топик, 422 - не прошёл валидацию,
+// 503 - не удалось записать в Kafka
+func (h *IngestHandler) IngestOrder(c *gin.Context) {
+	const op = "handler.IngestOrder"
+
+	body, err := readBody(c, maxOrderBodyBytes)
+	if err != nil {
+		respondBodyError(c, err)
+		return
+	}
+
+	var order models.Order
+	if err = gojson.Unmarshal(body, &order); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json: " + err.Error()})
+		return
+	}
+
+	if err = h.validator.Validate(h.log, &order); err != nil {
+		respondInvalid(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	//в топик уходит исходное тело: консьюмер разбирает его так же, как сообщения других продюсеров
+	//ключ order_uid: продюсер выбирает партицию по хешу ключа, события статуса этого заказа придут в ту же партицию
+	delivery, err := h.publisher.SendMessageWithDelivery(c.Request.Context(), h.topic, []byte(order.OrderUID), body, nil)
+	if err != nil {
+		h.log.Error("failed to publish order",
+			slog.String("op", op),
+			slog.String("order_uid", order.OrderUID),
+			slog.Any("error", err),
+		)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to publish order"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, IngestResult{
+		OrderUID:  order.OrderUID,
+		Topic:     delivery.Topic,
+		Partition: delivery.Partition,
+		Offset:    delivery.Offset,
+	})
+}
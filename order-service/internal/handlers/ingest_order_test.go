@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/handlers"
+	"order-service/internal/handlers/mocks"
+	"order-service/internal/kafka"
+	"order-service/internal/validator"
+)
+
+func setupIngestRouter(t *testing.T) (*gin.Engine, *mocks.OrderPublisher) {
+	t.Helper()
+	publisher := mocks.NewOrderPublisher(t)
+	h := handlers.NewIngestHandler(publisher, "orders", testLogger(), validator.New(validator.DefaultOptions))
+
+	r := gin.New()
+	r.POST("/ingest/orders", h.IngestOrder)
+	return r, publisher
+}
+
+func TestIngestOrder_Accepted(t *testing.T) {
+	r, publisher := setupIngestRouter(t)
+
+	body, err := json.Marshal(validOrder("uid-1"))
+	require.NoError(t, err)
+
+	publisher.On("SendMessageWithDelivery", mock.Anything, "orders", []byte("uid-1"), body, map[string]string(nil)).
+		Return(kafka.Delivery{Topic: "orders", Partition: 2, Offset: 42}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/ingest/orders", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.JSONEq(t, `{"order_uid":"uid-1","topic":"orders","partition":2,"offset":42}`, rec.Body.String())
+}
+
+func TestIngestOrder_Invalid(t *testing.T) {
+	r, publisher := setupIngestRouter(t)
+
+	order := validOrder("uid-1")
+	order.Payment.Currency = "RUR"
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, postJSON(t, "/ingest/orders", order))
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	publisher.AssertNotCalled(t, "SendMessageWithDelivery", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	var body struct {
+		Error  string                     `json:"error"`
+		Errors validator.ValidationErrors `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "bad_message", body.Error)
+	require.Len(t, body.Errors, 1)
+	assert.Equal(t, "payment.currency", body.Errors[0].Field)
+}
+
+func TestIngestOrder_BadJSON(t *testing.T) {
+	r, publisher := setupIngestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/ingest/orders", bytes.NewReader([]byte(`{"order_uid":`)))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	publisher.AssertNotCalled(t, "SendMessageWithDelivery", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIngestOrder_PublishError(t *testing.T) {
+	r, publisher := setupIngestRouter(t)
+
+	publisher.On("SendMessageWithDelivery", mock.Anything, "orders", []byte("uid-1"), mock.Anything, mock.Anything).
+		Return(kafka.Delivery{}, errors.New("leader not available")).Once()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, postJSON(t, "/ingest/orders", validOrder("uid-1")))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestIngestOrder_BodyTooLarge(t *testing.T) {
+	r, _ := setupIngestRouter(t)
+
+	order := validOrder("uid-1")
+	order.InternalSignature = string(bytes.Repeat([]byte("a"), 2<<20))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, postJSON(t, "/ingest/orders", order))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
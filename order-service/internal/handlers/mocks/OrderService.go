@@ -15,6 +15,54 @@ type OrderService struct {
 	mock.Mock
 }
 
+// CreateOrder provides a mock function with given fields: ctx, order
+func (_m *OrderService) CreateOrder(ctx context.Context, order *models.Order) error {
+	ret := _m.Called(ctx, order)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Order) error); ok {
+		r0 = rf(ctx, order)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetOrderByTrackNumber provides a mock function with given fields: ctx, trackNumber
+func (_m *OrderService) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	ret := _m.Called(ctx, trackNumber)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderByTrackNumber")
+	}
+
+	var r0 *models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Order, error)); ok {
+		return rf(ctx, trackNumber)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Order); ok {
+		r0 = rf(ctx, trackNumber)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, trackNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOrderByUID provides a mock function with given fields: ctx, orderUID
 func (_m *OrderService) GetOrderByUID(ctx context.Context, orderUID string) (*models.Order, error) {
 	ret := _m.Called(ctx, orderUID)
@@ -45,6 +93,36 @@ func (_m *OrderService) GetOrderByUID(ctx context.Context, orderUID string) (*mo
 	return r0, r1
 }
 
+// GetOrderHistory provides a mock function with given fields: ctx, orderUID
+func (_m *OrderService) GetOrderHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	ret := _m.Called(ctx, orderUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderHistory")
+	}
+
+	var r0 []models.StatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.StatusChange, error)); ok {
+		return rf(ctx, orderUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.StatusChange); ok {
+		r0 = rf(ctx, orderUID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PreloadCache provides a mock function with given fields: _a0, _a1
 func (_m *OrderService) PreloadCache(_a0 context.Context, _a1 int) error {
 	ret := _m.Called(_a0, _a1)
@@ -81,6 +159,36 @@ func (_m *OrderService) ProcessNewOrder(ctx context.Context, order *models.Order
 	return r0
 }
 
+// SearchOrders provides a mock function with given fields: ctx, filter
+func (_m *OrderService) SearchOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchOrders")
+	}
+
+	var r0 *models.OrderPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) (*models.OrderPage, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) *models.OrderPage); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OrderPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.OrderFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewOrderService creates a new instance of OrderService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewOrderService(t interface {
@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"order-service/internal/models"
 	"order-service/internal/repository"
+	"order-service/internal/validator"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	gojson "github.com/goccy/go-json"
@@ -14,19 +17,25 @@ import (
 
 type OrderService interface {
 	ProcessNewOrder(ctx context.Context, order *models.Order) error
+	CreateOrder(ctx context.Context, order *models.Order) error
 	GetOrderByUID(ctx context.Context, orderUID string) (*models.Order, error)
+	GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error)
+	GetOrderHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error)
+	SearchOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error)
 	PreloadCache(context.Context, int) error
 }
 
 type Handler struct {
-	service OrderService
-	log     *slog.Logger
+	service   OrderService
+	validator *validator.Validator
+	log       *slog.Logger
 }
 
-func NewHandler(service OrderService, log *slog.Logger) *Handler {
+func NewHandler(service OrderService, log *slog.Logger, orderValidator *validator.Validator) *Handler {
 	return &Handler{
-		service: service,
-		log:     log,
+		service:   service,
+		validator: orderValidator,
+		log:       log,
 	}
 }
 
@@ -67,3 +76,192 @@ func (h *Handler) GetOrderByUID(c *gin.Context) {
 		)
 	}
 }
+
+// GetOrderByTrack - обработчик для GET /track/:track_number
+func (h *Handler) GetOrderByTrack(c *gin.Context) {
+	const op = "handler.GetOrderByTrack"
+
+	trackNumber := c.Param("track_number")
+
+	if trackNumber == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "track_number is required"})
+		return
+	}
+
+	order, err := h.service.GetOrderByTrackNumber(c.Request.Context(), trackNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+
+		h.log.Error("failed to get order by track number",
+			slog.String("op", op),
+			slog.String("track_number", trackNumber),
+			slog.Any("error", err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	encoder := gojson.NewEncoder(c.Writer)
+	if err := encoder.Encode(order); err != nil {
+		h.log.Error("failed to encode order",
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// GetOrderHistory - обработчик для GET /order/:order_uid/history
+func (h *Handler) GetOrderHistory(c *gin.Context) {
+	const op = "handler.GetOrderHistory"
+
+	orderUID := c.Param("order_uid")
+
+	if orderUID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order_uid is required"})
+		return
+	}
+
+	history, err := h.service.GetOrderHistory(c.Request.Context(), orderUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+
+		h.log.Error("failed to get order history",
+			slog.String("op", op),
+			slog.String("order_uid", orderUID),
+			slog.Any("error", err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order_uid": orderUID,
+		"history":   history,
+	})
+}
+
+// SearchOrders - обработчик для GET /orders.
+// Фильтры: customer_id, track_number, phone, email, transaction, delivery_service, nm_id, brand,
+// date_from и date_to (RFC3339). Пагинация: limit и cursor из next_cursor предыдущей страницы
+func (h *Handler) SearchOrders(c *gin.Context) {
+	const op = "handler.SearchOrders"
+
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		respondInvalid(c, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := h.service.SearchOrders(c.Request.Context(), filter)
+	if err != nil {
+		h.log.Error("failed to search orders",
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetCustomerOrders - обработчик для GET /customers/:customer_id/orders.
+// Принимает те же фильтры и пагинацию, что и GET /orders
+func (h *Handler) GetCustomerOrders(c *gin.Context) {
+	const op = "handler.GetCustomerOrders"
+
+	customerID := c.Param("customer_id")
+
+	if customerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id is required"})
+		return
+	}
+
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		respondInvalid(c, http.StatusBadRequest, err)
+		return
+	}
+	filter.CustomerID = customerID
+
+	page, err := h.service.SearchOrders(c.Request.Context(), filter)
+	if err != nil {
+		h.log.Error("failed to get customer orders",
+			slog.String("op", op),
+			slog.String("customer_id", customerID),
+			slog.Any("error", err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func parseOrderFilter(c *gin.Context) (models.OrderFilter, error) {
+	filter := models.OrderFilter{
+		CustomerID:      c.Query("customer_id"),
+		TrackNumber:     c.Query("track_number"),
+		Phone:           c.Query("phone"),
+		Email:           c.Query("email"),
+		Transaction:     c.Query("transaction"),
+		DeliveryService: c.Query("delivery_service"),
+		Brand:           c.Query("brand"),
+	}
+
+	var errs validator.ValidationErrors
+	invalid := func(field, code, message string) {
+		errs = append(errs, validator.FieldError{Field: field, Code: code, Message: message})
+	}
+
+	var err error
+	if v := c.Query("nm_id"); v != "" {
+		if filter.NmID, err = strconv.Atoi(v); err != nil {
+			invalid("nm_id", validator.CodeInvalidFormat, "must be an integer")
+		}
+	}
+	if v := c.Query("date_from"); v != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339, v); err != nil {
+			invalid("date_from", validator.CodeInvalidFormat, "must be in RFC3339 format")
+		}
+	}
+	if v := c.Query("date_to"); v != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339, v); err != nil {
+			invalid("date_to", validator.CodeInvalidFormat, "must be in RFC3339 format")
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 {
+			invalid("limit", validator.CodeMustBePositive, "must be a positive integer")
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		if filter.After, err = models.DecodeCursor(v); err != nil {
+			invalid("cursor", validator.CodeInvalidValue, "is invalid")
+		}
+	}
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+	return filter, nil
+}
+
+// respondInvalid - ошибки валидации по полям в том же виде, что и в заголовке validation_errors DLQ.
+// 400 для параметров запроса, 422 для заказа в теле
+func respondInvalid(c *gin.Context, status int, err error) {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		c.JSON(status, gin.H{"error": validator.ErrBadMessage.Error(), "errors": verrs})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
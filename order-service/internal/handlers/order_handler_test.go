@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +18,7 @@ import (
 	"order-service/internal/handlers/mocks"
 	"order-service/internal/models"
 	"order-service/internal/repository"
+	"order-service/internal/validator"
 )
 
 func init() {
@@ -30,10 +32,16 @@ func testLogger() *slog.Logger {
 func setupRouter(t *testing.T) (*gin.Engine, *mocks.OrderService) {
 	t.Helper()
 	mockSvc := new(mocks.OrderService)
-	h := handlers.NewHandler(mockSvc, testLogger())
+	h := handlers.NewHandler(mockSvc, testLogger(), validator.New(validator.DefaultOptions))
 
 	r := gin.New()
 	r.GET("/order/:order_uid", h.GetOrderByUID)
+	r.GET("/order/:order_uid/history", h.GetOrderHistory)
+	r.GET("/orders", h.SearchOrders)
+	r.GET("/track/:track_number", h.GetOrderByTrack)
+	r.GET("/customers/:customer_id/orders", h.GetCustomerOrders)
+	r.POST("/order", h.CreateOrder)
+	r.POST("/orders/batch", h.CreateOrders)
 	return r, mockSvc
 }
 
@@ -101,3 +109,207 @@ func TestGetOrderByUID_ServiceError(t *testing.T) {
 	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
 	assert.Equal(t, "Internal server error", body["error"])
 }
+
+func TestGetOrderHistory_Success(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	history := []models.StatusChange{
+		{To: models.StatusCreated},
+		{From: models.StatusCreated, To: models.StatusPaid},
+	}
+	svc.On("GetOrderHistory", mock.Anything, "uid-123").
+		Return(history, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/order/uid-123/history", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		OrderUID string                `json:"order_uid"`
+		History  []models.StatusChange `json:"history"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "uid-123", body.OrderUID)
+	assert.Equal(t, history, body.History)
+}
+
+func TestGetOrderHistory_NotFound(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	svc.On("GetOrderHistory", mock.Anything, "missing").
+		Return(nil, repository.ErrNotFound).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/order/missing/history", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSearchOrders_Filters(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	after := &models.Cursor{DateCreated: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), OrderUID: "uid-9"}
+	want := models.OrderFilter{
+		CustomerID:      "cust1",
+		TrackNumber:     "TRK-1",
+		Phone:           "+79990000000",
+		Email:           "a@b.c",
+		Transaction:     "tx1",
+		DeliveryService: "meest",
+		CreatedFrom:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedTo:       time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		NmID:            42,
+		Brand:           "Vivienne Sabo",
+		Limit:           10,
+		After:           after,
+	}
+	page := &models.OrderPage{
+		Orders:     []*models.Order{{OrderUID: "uid-1"}},
+		NextCursor: "next",
+	}
+	svc.On("SearchOrders", mock.Anything, want).Return(page, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders?customer_id=cust1&track_number=TRK-1&phone=%2B79990000000"+
+		"&email=a@b.c&transaction=tx1&delivery_service=meest&date_from=2025-01-01T00:00:00Z&date_to=2025-02-01T00:00:00Z"+
+		"&nm_id=42&brand=Vivienne+Sabo&limit=10&cursor="+after.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body models.OrderPage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Orders, 1)
+	assert.Equal(t, "uid-1", body.Orders[0].OrderUID)
+	assert.Equal(t, "next", body.NextCursor)
+}
+
+func TestSearchOrders_BadRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{name: "nm_id", query: "nm_id=abc", code: validator.CodeInvalidFormat},
+		{name: "date_from", query: "date_from=2025-01-01", code: validator.CodeInvalidFormat},
+		{name: "date_to", query: "date_to=yesterday", code: validator.CodeInvalidFormat},
+		{name: "limit", query: "limit=0", code: validator.CodeMustBePositive},
+		{name: "cursor", query: "cursor=not-a-cursor", code: validator.CodeInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, svc := setupRouter(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			svc.AssertNotCalled(t, "SearchOrders", mock.Anything, mock.Anything)
+
+			var body struct {
+				Error  string                     `json:"error"`
+				Errors validator.ValidationErrors `json:"errors"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, "bad_message", body.Error)
+			require.Len(t, body.Errors, 1)
+			assert.Equal(t, tt.name, body.Errors[0].Field)
+			assert.Equal(t, tt.code, body.Errors[0].Code)
+		})
+	}
+}
+
+func TestSearchOrders_ServiceError(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	svc.On("SearchOrders", mock.Anything, models.OrderFilter{}).
+		Return(nil, assert.AnError).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestGetOrderByTrack_Success(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	order := &models.Order{OrderUID: "uid-123", TrackNumber: "TRK-1"}
+	svc.On("GetOrderByTrackNumber", mock.Anything, "TRK-1").Return(order, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/track/TRK-1", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got models.Order
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "uid-123", got.OrderUID)
+}
+
+func TestGetOrderByTrack_NotFound(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	svc.On("GetOrderByTrackNumber", mock.Anything, "missing").
+		Return((*models.Order)(nil), repository.ErrNotFound).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/track/missing", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetCustomerOrders_Success(t *testing.T) {
+	r, svc := setupRouter(t)
+	defer svc.AssertExpectations(t)
+
+	page := &models.OrderPage{Orders: []*models.Order{{OrderUID: "uid-1", CustomerID: "cust1"}}}
+	// customer_id из пути перекрывает одноимённый query-параметр
+	svc.On("SearchOrders", mock.Anything, models.OrderFilter{CustomerID: "cust1", Limit: 5}).
+		Return(page, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/customers/cust1/orders?limit=5&customer_id=other", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body models.OrderPage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Orders, 1)
+	assert.Equal(t, "uid-1", body.Orders[0].OrderUID)
+}
+
+func TestGetCustomerOrders_BadRequest(t *testing.T) {
+	r, svc := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/customers/cust1/orders?limit=-1", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	svc.AssertNotCalled(t, "SearchOrders", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc - проверка одного компонента. nil - компонент готов
+type CheckFunc func(ctx context.Context) error
+
+// ComponentStatus - результат проверки компонента
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report - ответ /readyz
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker - набор проверок готовности сервиса
+type Checker struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add - регистрирует проверку компонента. Вызывается до запуска HTTP-сервера
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Check - параллельно выполняет все проверки, каждую не дольше timeout
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status:     StatusUp,
+		Components: make(map[string]ComponentStatus, len(c.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx := ctx
+			if c.timeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, c.timeout)
+				defer cancel()
+			}
+
+			status := ComponentStatus{Status: StatusUp}
+			if err := nc.check(checkCtx); err != nil {
+				status = ComponentStatus{Status: StatusDown, Error: err.Error()}
+			}
+
+			mu.Lock()
+			report.Components[nc.name] = status
+			if status.Status == StatusDown {
+				report.Status = StatusDown
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Liveness - обработчик для GET /healthz: процесс жив и обслуживает запросы
+func (c *Checker) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": StatusUp})
+}
+
+// Readiness - обработчик для GET /readyz: 200, если все компоненты готовы, иначе 503
+func (c *Checker) Readiness(ctx *gin.Context) {
+	report := c.Check(ctx.Request.Context())
+
+	code := http.StatusOK
+	if report.Status != StatusUp {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, report)
+}
+
+// Flag - признак завершения этапа запуска, например предзагрузки кеша
+type Flag struct {
+	done atomic.Bool
+}
+
+// Set - отмечает этап завершённым
+func (f *Flag) Set() {
+	f.done.Store(true)
+}
+
+// Check - проверка для Checker.Add
+func (f *Flag) Check(reason string) CheckFunc {
+	return func(context.Context) error {
+		if !f.done.Load() {
+			return errors.New(reason)
+		}
+		return nil
+	}
+}
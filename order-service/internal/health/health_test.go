@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func setupRouter(c *Checker) *gin.Engine {
+	r := gin.New()
+	r.GET("/healthz", c.Liveness)
+	r.GET("/readyz", c.Readiness)
+	return r
+}
+
+func TestChecker_AllUp(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("postgres", func(context.Context) error { return nil })
+	c.Add("kafka", func(context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	setupRouter(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, Report{
+		Status: StatusUp,
+		Components: map[string]ComponentStatus{
+			"postgres": {Status: StatusUp},
+			"kafka":    {Status: StatusUp},
+		},
+	}, report)
+}
+
+func TestChecker_ComponentDown(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("postgres", func(context.Context) error { return nil })
+	c.Add("kafka", func(context.Context) error { return errors.New("connection refused") })
+
+	rec := httptest.NewRecorder()
+	setupRouter(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, ComponentStatus{Status: StatusUp}, report.Components["postgres"])
+	assert.Equal(t, ComponentStatus{Status: StatusDown, Error: "connection refused"}, report.Components["kafka"])
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := NewChecker(10 * time.Millisecond)
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := c.Check(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["slow"].Error)
+}
+
+func TestChecker_Liveness(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("kafka", func(context.Context) error { return errors.New("down") })
+
+	rec := httptest.NewRecorder()
+	setupRouter(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	// живость не зависит от зависимостей
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestFlag(t *testing.T) {
+	var f Flag
+	check := f.Check("not ready")
+
+	assert.EqualError(t, check(context.Background()), "not ready")
+
+	f.Set()
+	assert.NoError(t, check(context.Background()))
+}
@@ -0,0 +1,148 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"order-service/internal/metrics"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// batching - включён ли пакетный режим. Retry-топики всегда обрабатываются по одному сообщению,
+// потому что каждое из них выдерживает собственную задержку
+func (c *Consumer) batching() bool {
+	return c.batchSize > 1 && c.stage == 0
+}
+
+// runBatchWorker - копит сообщения воркера, пока не наберётся batchSize или не пройдёт batchTimeout
+// с момента первого сообщения в пачке, и обрабатывает их одной транзакцией
+func (c *Consumer) runBatchWorker(ctx context.Context, msgs <-chan kafka.Message) {
+	batch := make([]kafka.Message, 0, c.batchSize)
+	timer := time.NewTimer(c.batchTimeout)
+	timer.Stop()
+	defer timer.Stop()
+
+	//false - пачка не закоммичена, следующие пачки коммитить нельзя: их offset'ы перекрыли бы незакоммиченные
+	flush := func() bool {
+		timer.Stop()
+		if len(batch) == 0 {
+			return true
+		}
+		ok := c.handleBatch(ctx, batch)
+		batch = batch[:0]
+		return ok
+	}
+
+	for {
+		select {
+		case m, ok := <-msgs:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(c.batchTimeout)
+			}
+			batch = append(batch, m)
+			if len(batch) >= c.batchSize && !flush() {
+				return
+			}
+		case <-timer.C:
+			if !flush() {
+				return
+			}
+		}
+	}
+}
+
+// handleBatch - сохраняет валидные заказы пачки одной транзакцией и коммитит offset'ы пачки одним вызовом.
+// Если пачку сохранить не удалось, её сообщения обрабатываются по одному с обычной политикой ретраев.
+// Возвращает false, если пачка не доведена до коммита из-за остановки консьюмера
+func (c *Consumer) handleBatch(ctx context.Context, batch []kafka.Message) bool {
+	const op = "kafka.handleBatch"
+	log := c.logger.With(slog.String("op", op), slog.Int("size", len(batch)))
+
+	//консьюмер останавливается: пачка ещё не начата, её дочитает следующий запуск
+	if ctx.Err() != nil {
+		return false
+	}
+	//пачка уже набрана, поэтому при остановке она обрабатывается и коммитится целиком
+	work := context.WithoutCancel(ctx)
+
+	orders := make([]*models.Order, 0, len(batch))
+	orderIdx := make([]int, 0, len(batch))
+	//сообщения, которые придётся обработать по одному: события статуса, не удалось отправить в DLQ
+	//или не сохранилась пачка
+	fallback := make([]bool, len(batch))
+	for i, m := range batch {
+		//события статуса обрабатываются по одному уже после записи пачки заказов
+		if isStatusEvent(m) {
+			fallback[i] = true
+			continue
+		}
+
+		order, err := c.decodeMessage(work, m)
+		switch {
+		case err != nil:
+			fallback[i] = true
+		case order != nil:
+			orders = append(orders, order)
+			orderIdx = append(orderIdx, i)
+		}
+	}
+
+	if len(orders) > 0 {
+		if err := c.saveBatch(ctx, orders); err != nil {
+			log.Warn("failed to save batch, falling back to per-message processing", slog.Any("error", err))
+			for _, i := range orderIdx {
+				fallback[i] = true
+			}
+		}
+	}
+
+	//идём в исходном порядке, чтобы не нарушить порядок внутри партиции
+	for i, m := range batch {
+		if !fallback[i] {
+			continue
+		}
+		if err := c.deliver(ctx, m); err != nil {
+			//пачка не закоммичена и будет прочитана заново после перезапуска
+			log.Error("failed to deliver message", slog.Any("error", err), slog.Int64("offset", m.Offset))
+			return false
+		}
+	}
+
+	if err := c.reader.CommitMessages(work, batch...); err != nil {
+		log.Error("failed to commit batch", slog.Any("error", err))
+		return true
+	}
+	for _, m := range batch {
+		metrics.KafkaMessagesProcessed.WithLabelValues(m.Topic).Inc()
+	}
+	return true
+}
+
+// saveBatch - сохраняет пачку заказов с повторами по политике ретраев
+func (c *Consumer) saveBatch(ctx context.Context, orders []*models.Order) error {
+	var err error
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if errSleep := sleepCtx(ctx, c.retry.backoff(attempt-1)); errSleep != nil {
+				return errSleep
+			}
+		}
+
+		if err = c.service.ProcessNewOrders(context.WithoutCancel(ctx), orders); err == nil {
+			return nil
+		}
+		//в пачке есть уже сохранённые заказы - их разберёт обработка по одному
+		if errors.Is(err, repository.ErrOrderExists) || errors.Is(err, repository.ErrOrderChanged) {
+			return err
+		}
+	}
+	return err
+}
@@ -6,9 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"order-service/internal/metrics"
 	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/service"
 	"order-service/internal/validator"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -17,6 +22,8 @@ import (
 // интерфейс сервисного слоя
 type OrderService interface {
 	ProcessNewOrder(context.Context, *models.Order) error
+	ProcessNewOrders(context.Context, []*models.Order) error
+	UpdateOrderStatus(context.Context, *models.StatusEvent) error
 	GetOrderByUID(context.Context, string) (*models.Order, error)
 	PreloadCache(context.Context, int) error
 }
@@ -25,12 +32,32 @@ type DLQProducer interface {
 	SendMessage(ctx context.Context, topic string, key, value []byte, headers map[string]string) error
 }
 
+// messageReader - часть kafka.Reader, которой пользуется консьюмер
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Config() kafka.ReaderConfig
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
 type Consumer struct {
-	reader      *kafka.Reader
+	reader      messageReader
 	logger      *slog.Logger
 	service     OrderService
 	dlqProducer DLQProducer
 	dlqTopic    string
+	concurrency int
+	retry       RetryPolicy
+	stage       int
+	stageDelay  time.Duration
+
+	batchSize    int
+	batchTimeout time.Duration
+
+	validator *validator.Validator
+
+	running atomic.Bool
 }
 
 func NewConsumer(
@@ -42,7 +69,19 @@ func NewConsumer(
 	service OrderService,
 	dlqTopic string,
 	dlqProducer DLQProducer,
+	concurrency int,
+	retry RetryPolicy,
+	batchSize int,
+	batchTimeout time.Duration,
+	orderValidator *validator.Validator,
 ) *Consumer {
+	//консьюмер retry-топика знает свою ступень и выдерживает её задержку.
+	//Для retry-топиков используется отдельная группа, чтобы их ребалансы не затрагивали основной топик
+	stage, stageDelay := retry.stageOf(topic)
+	if stage > 0 {
+		groupID = groupID + "-" + topic
+	}
+
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:        brokers,
 		GroupID:        groupID,
@@ -60,18 +99,61 @@ func NewConsumer(
 		service:     service,
 		dlqTopic:    dlqTopic,
 		dlqProducer: dlqProducer,
+		concurrency: max(concurrency, 1),
+		retry:       retry,
+		stage:       stage,
+		stageDelay:  stageDelay,
+
+		batchSize:    batchSize,
+		batchTimeout: batchTimeout,
+
+		validator: orderValidator,
 	}
 }
 
-// Start - запускает бесконечный цикл чтения сообщений из топика
+// Start - запускает бесконечный цикл чтения сообщений из топика.
+// Сообщения раздаются воркерам по номеру партиции: одна партиция всегда обрабатывается
+// одним воркером, поэтому порядок обработки и коммитов внутри партиции сохраняется,
+// а разные партиции обрабатываются параллельно.
+// После отмены ctx новые сообщения не читаются, а Start возвращается, когда воркеры
+// доведут до коммита сообщения, которые уже обрабатывают
 func (c *Consumer) Start(ctx context.Context) {
+	c.running.Store(true)
+	defer c.running.Store(false)
 	defer c.reader.Close()
 	c.logger.Info("Kafka consumer started",
 		slog.String("topic", c.reader.Config().Topic),
 		slog.String("group", c.reader.Config().GroupID),
 		slog.Any("brokers", c.reader.Config().Brokers),
+		slog.Int("concurrency", c.concurrency),
+		slog.Bool("batching", c.batching()),
 	)
 
+	var wg sync.WaitGroup
+	workers := make([]chan kafka.Message, c.concurrency)
+	for i := range workers {
+		workers[i] = make(chan kafka.Message, 1)
+		wg.Add(1)
+		go func(msgs <-chan kafka.Message) {
+			defer wg.Done()
+			if c.batching() {
+				c.runBatchWorker(ctx, msgs)
+				return
+			}
+			for m := range msgs {
+				c.handleMessage(ctx, m)
+			}
+		}(workers[i])
+	}
+
+	//дожидаемся, пока воркеры закончат текущие сообщения
+	defer func() {
+		for _, w := range workers {
+			close(w)
+		}
+		wg.Wait()
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -90,28 +172,195 @@ func (c *Consumer) Start(ctx context.Context) {
 				continue
 			}
 
-			//обработка сообщения
-			if err = c.processMessage(ctx, m); err != nil {
-				c.logger.Error("failed to process message, will retry",
-					slog.Any("error", err),
-					slog.Int("partition", m.Partition),
-					slog.Int64("offset", m.Offset),
-				)
-				continue
+			select {
+			case workers[m.Partition%c.concurrency] <- m:
+			case <-ctx.Done():
+				return
 			}
+		}
+	}
+}
+
+// handleMessage - обрабатывает сообщение и коммитит его offset.
+// Offset коммитится только после того, как сообщение обработано или передано дальше
+func (c *Consumer) handleMessage(ctx context.Context, m kafka.Message) {
+	//консьюмер останавливается: сообщение ещё не начато, его дочитает следующий запуск
+	if ctx.Err() != nil {
+		return
+	}
 
-			//коммит msg
-			if err = c.reader.CommitMessages(ctx, m); err != nil {
-				c.logger.Error("failed to commit message", slog.Any("error", err))
+	if err := c.deliver(ctx, m); err != nil {
+		//сообщение не закоммичено и будет прочитано заново после перезапуска
+		c.logger.Error("failed to deliver message", slog.Any("error", err))
+		return
+	}
+
+	//коммит msg; начатое сообщение коммитится и во время остановки
+	if err := c.reader.CommitMessages(context.WithoutCancel(ctx), m); err != nil {
+		c.logger.Error("failed to commit message", slog.Any("error", err))
+		return
+	}
+	metrics.KafkaMessagesProcessed.WithLabelValues(m.Topic).Inc()
+}
+
+// ReaderStats - статистика kafka.Reader для метрик
+func (c *Consumer) ReaderStats() kafka.ReaderStats {
+	return c.reader.Stats()
+}
+
+// deliver - доводит сообщение до конечного состояния без коммита offset'а.
+// При временной ошибке сообщение повторно обрабатывается с backoff, затем пересылается
+// на следующую ступень retry-топиков или в DLQ. Ошибка возвращается, только если не удалось ни то, ни другое.
+// Отмена ctx прерывает только ожидания: начатая обработка доводится до конца, чтобы не бросать её на полпути
+func (c *Consumer) deliver(ctx context.Context, m kafka.Message) error {
+	work := context.WithoutCancel(ctx)
+
+	if c.stageDelay > 0 {
+		//сообщения в retry-топике идут по порядку, поэтому можно просто дождаться его очереди
+		if err := sleepCtx(ctx, time.Until(m.Time.Add(c.stageDelay))); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if errSleep := sleepCtx(ctx, c.retry.backoff(attempt-1)); errSleep != nil {
+				return errSleep
 			}
 		}
+
+		//обработка сообщения
+		if err = c.processMessage(work, m); err == nil {
+			return nil
+		}
+		metrics.KafkaMessagesFailed.WithLabelValues(m.Topic).Inc()
+		c.logger.Warn("failed to process message",
+			slog.Any("error", err),
+			slog.Int("partition", m.Partition),
+			slog.Int64("offset", m.Offset),
+			slog.Int("attempt", attempt+1),
+		)
 	}
+
+	return c.forwardForRetry(ctx, m, err)
+}
+
+// forwardForRetry - пересылает сообщение на следующую ступень ретраев,
+// а после последней ступени - в DLQ. Отправка повторяется, пока не удастся или не отменят контекст
+func (c *Consumer) forwardForRetry(ctx context.Context, m kafka.Message, cause error) error {
+	const op = "kafka.forwardForRetry"
+
+	headers := originHeaders(m)
+	headers[HeaderRetryAttempt] = strconv.Itoa(retryAttempt(m) + 1)
+	headers[HeaderErrorDetails] = cause.Error()
+
+	topic := c.dlqTopic
+	if c.stage < len(c.retry.Stages) {
+		topic = c.retry.Stages[c.stage].Topic
+	} else {
+		headers[HeaderErrorReason] = "retries_exhausted"
+	}
+
+	log := c.logger.With(
+		slog.String("op", op),
+		slog.String("topic", topic),
+		slog.Int("partition", m.Partition),
+		slog.Int64("offset", m.Offset),
+		slog.String("retry_attempt", headers[HeaderRetryAttempt]),
+	)
+
+	for attempt := 0; ; attempt++ {
+		err := c.dlqProducer.SendMessage(context.WithoutCancel(ctx), topic, m.Key, m.Value, headers)
+		if err == nil {
+			if topic == c.dlqTopic {
+				metrics.KafkaMessagesDLQ.WithLabelValues(headers[HeaderErrorReason]).Inc()
+			} else {
+				metrics.KafkaMessagesRetried.WithLabelValues(topic).Inc()
+			}
+			log.Warn("message forwarded for retry")
+			return nil
+		}
+		log.Error("failed to forward message", slog.Any("error", err))
+
+		if errSleep := sleepCtx(ctx, c.retry.backoff(attempt)); errSleep != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+}
+
+// sendToDLQ - отправляет невалидное сообщение в DLQ с причиной ошибки
+func (c *Consumer) sendToDLQ(ctx context.Context, msg kafka.Message, reason, details string) error {
+	headers := originHeaders(msg)
+	headers[HeaderErrorReason] = reason
+	headers[HeaderErrorDetails] = details
+
+	return c.publishToDLQ(ctx, msg, headers)
+}
+
+// sendInvalidToDLQ - отправляет в DLQ сообщение, не прошедшее валидацию.
+// Ошибки по полям дополнительно кладутся в заголовок HeaderValidationErrors
+func (c *Consumer) sendInvalidToDLQ(ctx context.Context, msg kafka.Message, err error) error {
+	headers := originHeaders(msg)
+	headers[HeaderErrorReason] = "validation_failed"
+	headers[HeaderErrorDetails] = err.Error()
+
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		headers[HeaderValidationErrors] = verrs.JSON()
+	}
+
+	return c.publishToDLQ(ctx, msg, headers)
+}
+
+func (c *Consumer) publishToDLQ(ctx context.Context, msg kafka.Message, headers map[string]string) error {
+	if errDLQ := c.dlqProducer.SendMessage(ctx, c.dlqTopic, msg.Key, msg.Value, headers); errDLQ != nil {
+		// Возвращаем ошибку, чтобы сообщение не было закоммичено
+		c.logger.Error("CRITICAL: FAILED TO SEND MESSAGE TO DLQ", slog.Any("dlq_error", errDLQ))
+		return fmt.Errorf("failed to send to DLQ: %w", errDLQ)
+	}
+	metrics.KafkaMessagesDLQ.WithLabelValues(headers[HeaderErrorReason]).Inc()
+	return nil
 }
 
 // processMessage - инкапсулирует логику парсинга, валидции и передачи msg в сервис
 func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) error {
 	const op = "kafka.processMessage"
 
+	//события смены статуса идут в том же топике с тем же ключом, что и заказ,
+	//поэтому обрабатываются строго после него
+	if isStatusEvent(msg) {
+		return c.processStatusMessage(ctx, msg)
+	}
+
+	order, err := c.decodeMessage(ctx, msg)
+	if err != nil || order == nil {
+		return err
+	}
+
+	if err = c.service.ProcessNewOrder(ctx, order); err != nil {
+		//изменённый повтор уже сохранённого заказа отклонён политикой конфликтов - ретраи не помогут
+		if errors.Is(err, repository.ErrOrderChanged) {
+			return c.sendToDLQ(ctx, msg, "order_changed", err.Error())
+		}
+		// Тут не стоит сразу отправлять в DLQ, потому что может быть временная ошибка (например, бд недоступна),
+		// такие сообщения уходят на повторную обработку в deliver
+		return fmt.Errorf("%s: failed to process order: %w", op, err)
+	}
+	c.logger.Debug("order processed successfully",
+		slog.String("order_uid", order.OrderUID),
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+	)
+
+	return nil
+}
+
+// decodeMessage - парсит и валидирует сообщение. Невалидные сообщения отправляются в DLQ,
+// в этом случае возвращается nil без ошибки. Ошибка означает, что не удалось отправить в DLQ
+func (c *Consumer) decodeMessage(ctx context.Context, msg kafka.Message) (*models.Order, error) {
+	const op = "kafka.decodeMessage"
+
 	var order models.Order
 
 	if err := json.Unmarshal(msg.Value, &order); err != nil {
@@ -122,20 +371,7 @@ func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) error
 			slog.String("operation", op),
 		)
 
-		//формируем headers для отправки сообщения в DLQ
-		headers := map[string]string{
-			"error_reason":    "json_unmarshal_failed",
-			"error_details":   err.Error(),
-			"original_topic":  msg.Topic,
-			"original_offset": strconv.FormatInt(msg.Offset, 10),
-		}
-
-		if errDLQ := c.dlqProducer.SendMessage(ctx, c.dlqTopic, msg.Key, msg.Value, headers); errDLQ != nil {
-			// Возвращаем ошибку, чтобы сообщение не было закоммичено
-			c.logger.Error("CRITICAL: FAILED TO SEND MESSAGE TO DLQ", slog.Any("dlq_error", errDLQ))
-			return fmt.Errorf("failed to send to DLQ: %w", errDLQ)
-		}
-		return nil
+		return nil, c.sendToDLQ(ctx, msg, "json_unmarshal_failed", err.Error())
 	}
 
 	log := c.logger.With(
@@ -146,29 +382,43 @@ func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) error
 	)
 
 	//валидация данных
-	if err := validator.Validate(log, &order); err != nil {
+	if err := c.validator.Validate(log, &order); err != nil {
 		if errors.Is(err, validator.ErrBadMessage) {
-			headers := map[string]string{
-				"error_reason":    "validation_failed",
-				"error_details":   err.Error(),
-				"original_topic":  msg.Topic,
-				"original_offset": strconv.FormatInt(msg.Offset, 10),
-			}
-
-			if errDLQ := c.dlqProducer.SendMessage(ctx, c.dlqTopic, msg.Key, msg.Value, headers); errDLQ != nil {
-				c.logger.Error("CRITICAL: FAILED TO SEND MESSAGE TO DLQ", slog.Any("dlq_error", errDLQ))
-				return fmt.Errorf("failed to send to DLQ: %w", errDLQ)
-			}
+			return nil, c.sendInvalidToDLQ(ctx, msg, err)
 		}
-		return nil
+		return nil, nil
 	}
 	log.Debug("processing new order")
 
-	if err := c.service.ProcessNewOrder(ctx, &order); err != nil {
-		// Тут не стоит сразу отправлять в DLQ, потому что может быть временная ошибка (например, бд недоступна)
-		return fmt.Errorf("%s: failed to process order: %w", op, err)
+	return &order, nil
+}
+
+// processStatusMessage - парсит, валидирует событие смены статуса и передаёт его в сервис.
+// Недопустимый переход не исправится повторной обработкой, поэтому такое событие сразу уходит в DLQ
+func (c *Consumer) processStatusMessage(ctx context.Context, msg kafka.Message) error {
+	const op = "kafka.processStatusMessage"
+
+	var event models.StatusEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		c.logger.Error("invalid status event json, skipping",
+			slog.Any("error", err),
+			slog.Int("partition", msg.Partition),
+			slog.Int64("offset", msg.Offset),
+			slog.String("operation", op),
+		)
+		return c.sendToDLQ(ctx, msg, "json_unmarshal_failed", err.Error())
+	}
+
+	if err := validator.ValidateStatusEvent(&event); err != nil {
+		return c.sendInvalidToDLQ(ctx, msg, err)
+	}
+
+	if err := c.service.UpdateOrderStatus(ctx, &event); err != nil {
+		if errors.Is(err, service.ErrInvalidTransition) {
+			return c.sendToDLQ(ctx, msg, "invalid_status_transition", err.Error())
+		}
+		return fmt.Errorf("%s: failed to update order status: %w", op, err)
 	}
-	log.Debug("order processed successfully")
 
 	return nil
 }
@@ -0,0 +1,480 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/validator"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroker - топик в памяти с закоммиченными offset'ами группы. Каждый новый reader
+// начинает читать партиции с последнего коммита, как консьюмер группы после перезапуска
+type fakeBroker struct {
+	mu         sync.Mutex
+	partitions [][]kafka.Message
+	committed  map[int]int64
+	commits    map[string]int
+	//offset'ы коммитов каждой партиции в порядке вызова CommitMessages
+	commitLog map[int][]int64
+}
+
+func newFakeBroker(t *testing.T, partitions, perPartition int) *fakeBroker {
+	b := &fakeBroker{
+		partitions: make([][]kafka.Message, partitions),
+		committed:  make(map[int]int64),
+		commits:    make(map[string]int),
+		commitLog:  make(map[int][]int64),
+	}
+	for p := 0; p < partitions; p++ {
+		b.committed[p] = -1
+		for off := 0; off < perPartition; off++ {
+			uid := fmt.Sprintf("order-%d-%d", p, off)
+			value, err := json.Marshal(validOrder(uid))
+			require.NoError(t, err)
+			b.partitions[p] = append(b.partitions[p], kafka.Message{
+				Topic:     "orders",
+				Partition: p,
+				Offset:    int64(off),
+				Key:       []byte(uid),
+				Value:     value,
+				Time:      time.Now(),
+			})
+		}
+	}
+	return b
+}
+
+func (b *fakeBroker) reader() *fakeReader {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := &fakeReader{broker: b, next: make([]int64, len(b.partitions))}
+	for p := range b.partitions {
+		r.next[p] = b.committed[p] + 1
+	}
+	return r
+}
+
+type fakeReader struct {
+	broker *fakeBroker
+	mu     sync.Mutex
+	next   []int64
+	turn   int
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	//партиции читаются по очереди, чтобы сообщения доставались разным воркерам
+	for i := 0; i < len(r.next); i++ {
+		p := (r.turn + i) % len(r.next)
+		if int(r.next[p]) < len(r.broker.partitions[p]) {
+			m := r.broker.partitions[p][r.next[p]]
+			r.next[p]++
+			r.turn = p + 1
+			r.mu.Unlock()
+			return m, nil
+		}
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := r.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, m := range msgs {
+		b.commits[string(m.Key)]++
+		b.committed[m.Partition] = max(b.committed[m.Partition], m.Offset)
+		b.commitLog[m.Partition] = append(b.commitLog[m.Partition], m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Config() kafka.ReaderConfig { return kafka.ReaderConfig{Topic: "orders"} }
+func (r *fakeReader) Stats() kafka.ReaderStats   { return kafka.ReaderStats{} }
+func (r *fakeReader) Close() error               { return nil }
+
+// slowService - сохраняет заказы с задержкой, как запись в бд, и прерывается по отмене контекста
+type slowService struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	started   int
+	processed map[string]int
+}
+
+func (s *slowService) save(ctx context.Context, orders ...*models.Order) error {
+	s.mu.Lock()
+	s.started += len(orders)
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range orders {
+		s.processed[o.OrderUID]++
+	}
+	return nil
+}
+
+func (s *slowService) ProcessNewOrder(ctx context.Context, order *models.Order) error {
+	return s.save(ctx, order)
+}
+
+func (s *slowService) ProcessNewOrders(ctx context.Context, orders []*models.Order) error {
+	return s.save(ctx, orders...)
+}
+
+func (s *slowService) UpdateOrderStatus(context.Context, *models.StatusEvent) error { return nil }
+
+func (s *slowService) GetOrderByUID(context.Context, string) (*models.Order, error) { return nil, nil }
+
+func (s *slowService) PreloadCache(context.Context, int) error { return nil }
+
+func (s *slowService) snapshot() (started int, processed map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	processed = make(map[string]int, len(s.processed))
+	for k, v := range s.processed {
+		processed[k] = v
+	}
+	return s.started, processed
+}
+
+type failingDLQ struct{ t *testing.T }
+
+func (d failingDLQ) SendMessage(context.Context, string, []byte, []byte, map[string]string) error {
+	d.t.Error("unexpected message forwarded to DLQ")
+	return nil
+}
+
+func validOrder(uid string) *models.Order {
+	return &models.Order{
+		OrderUID:        uid,
+		TrackNumber:     "TRK-" + uid,
+		Entry:           "WBIL",
+		DeliveryService: "meest",
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Delivery: models.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction: uid, Currency: "USD", Provider: "wbpay", Amount: 1817, PaymentDT: 1637907727,
+			DeliveryCost: 1500, GoodsTotal: 317,
+		},
+		Items: []models.Item{{
+			ChrtID: 9934930, TrackNumber: "TRK-" + uid, Price: 453, Rid: "rid", Name: "Mascaras",
+			Sale: 30, TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202,
+		}},
+	}
+}
+
+func newTestConsumer(t *testing.T, r messageReader, svc OrderService, batchSize int) *Consumer {
+	return &Consumer{
+		reader:       r,
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		service:      svc,
+		dlqProducer:  failingDLQ{t: t},
+		dlqTopic:     "orders_dlq",
+		concurrency:  2,
+		retry:        RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		batchSize:    batchSize,
+		batchTimeout: 5 * time.Millisecond,
+		validator:    validator.New(validator.DefaultOptions),
+	}
+}
+
+// runUntil - запускает консьюмер и останавливает его, как только done вернёт true
+func runUntil(t *testing.T, c *Consumer, done func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(stopped)
+	}()
+
+	require.Eventually(t, done, 5*time.Second, time.Millisecond)
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not stop")
+	}
+	assert.False(t, c.Running())
+}
+
+func TestConsumer_ShutdownLosesAndDuplicatesNothing(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+	}{
+		{name: "per message", batchSize: 0},
+		{name: "batch", batchSize: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const partitions, perPartition = 2, 20
+			broker := newFakeBroker(t, partitions, perPartition)
+			svc := &slowService{delay: 5 * time.Millisecond, processed: make(map[string]int)}
+
+			//останавливаем первый запуск посреди обработки
+			runUntil(t, newTestConsumer(t, broker.reader(), svc, tt.batchSize), func() bool {
+				started, _ := svc.snapshot()
+				return started >= 5
+			})
+
+			started, processed := svc.snapshot()
+			//начатая обработка доводится до конца, а не обрывается отменой контекста
+			assert.Equal(t, started, len(processed), "in-flight processing was abandoned")
+			require.Less(t, len(processed), partitions*perPartition, "shutdown came too late to test anything")
+
+			//закоммичено ровно то, что обработано: иначе после перезапуска сообщение потеряется или обработается дважды
+			broker.mu.Lock()
+			for uid := range processed {
+				assert.Equal(t, 1, broker.commits[uid], "processed but not committed: %s", uid)
+			}
+			assert.Len(t, broker.commits, len(processed))
+			broker.mu.Unlock()
+
+			//перезапуск дочитывает остаток с закоммиченных offset'ов
+			runUntil(t, newTestConsumer(t, broker.reader(), svc, tt.batchSize), func() bool {
+				_, processed := svc.snapshot()
+				return len(processed) == partitions*perPartition
+			})
+
+			_, processed = svc.snapshot()
+			for p := 0; p < partitions; p++ {
+				for off := 0; off < perPartition; off++ {
+					uid := fmt.Sprintf("order-%d-%d", p, off)
+					assert.Equal(t, 1, processed[uid], "order %s", uid)
+				}
+			}
+		})
+	}
+}
+
+// stopOnFailService - пачки не сохраняются, а заказ failUID падает и останавливает консьюмер,
+// пока его обработка повторяется: deliver вернёт ошибку отмены
+type stopOnFailService struct {
+	slowService
+	failUID string
+	stop    context.CancelFunc
+}
+
+func (s *stopOnFailService) ProcessNewOrders(context.Context, []*models.Order) error {
+	return errors.New("batch insert failed")
+}
+
+func (s *stopOnFailService) ProcessNewOrder(ctx context.Context, order *models.Order) error {
+	if order.OrderUID == s.failUID {
+		s.stop()
+		return errors.New("db unavailable")
+	}
+	return s.save(ctx, order)
+}
+
+func TestConsumer_FailedBatchStopsCommits(t *testing.T) {
+	broker := newFakeBroker(t, 1, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := &stopOnFailService{
+		slowService: slowService{processed: make(map[string]int)},
+		failUID:     "order-0-0",
+		stop:        cancel,
+	}
+	c := newTestConsumer(t, broker.reader(), svc, 4)
+
+	//обе пачки уже прочитаны воркером, как при остановке посреди чтения
+	msgs := make(chan kafka.Message, 8)
+	for _, m := range broker.partitions[0] {
+		msgs <- m
+	}
+	close(msgs)
+
+	done := make(chan struct{})
+	go func() {
+		c.runBatchWorker(ctx, msgs)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop")
+	}
+
+	//первая пачка не доведена до коммита, коммит второй сдвинул бы offset за недоставленный заказ
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Empty(t, broker.commitLog[0])
+	assert.Equal(t, int64(-1), broker.committed[0])
+	_, processed := svc.snapshot()
+	assert.NotContains(t, processed, "order-0-4")
+}
+
+// orderingService - запоминает порядок обработки по партициям и проверяет, что воркер
+// (партиция % concurrency) не обрабатывает два сообщения одновременно
+type orderingService struct {
+	slowService
+	concurrency int
+	active      []int
+	total       int
+	overlapped  bool
+	seen        map[int][]int64
+	violations  []string
+}
+
+func newOrderingService(concurrency int) *orderingService {
+	return &orderingService{
+		slowService: slowService{processed: make(map[string]int)},
+		concurrency: concurrency,
+		active:      make([]int, concurrency),
+		seen:        make(map[int][]int64),
+	}
+}
+
+func (s *orderingService) ProcessNewOrder(_ context.Context, order *models.Order) error {
+	var p int
+	var off int64
+	if _, err := fmt.Sscanf(order.OrderUID, "order-%d-%d", &p, &off); err != nil {
+		return err
+	}
+	worker := p % s.concurrency
+
+	s.mu.Lock()
+	s.active[worker]++
+	if s.active[worker] > 1 {
+		s.violations = append(s.violations, fmt.Sprintf("worker %d got %s while busy", worker, order.OrderUID))
+	}
+	s.total++
+	if s.total > 1 {
+		s.overlapped = true
+	}
+	s.seen[p] = append(s.seen[p], off)
+	s.processed[order.OrderUID]++
+	s.mu.Unlock()
+
+	time.Sleep(2 * time.Millisecond)
+
+	s.mu.Lock()
+	s.active[worker]--
+	s.total--
+	s.mu.Unlock()
+	return nil
+}
+
+func TestConsumer_PartitionsRoutedToWorkers(t *testing.T) {
+	const partitions, perPartition = 4, 10
+	broker := newFakeBroker(t, partitions, perPartition)
+	svc := newOrderingService(2)
+	c := newTestConsumer(t, broker.reader(), svc, 0)
+
+	runUntil(t, c, func() bool {
+		_, processed := svc.snapshot()
+		return len(processed) == partitions*perPartition
+	})
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	//партиции 0 и 2, 1 и 3 делят воркер и не обрабатываются одновременно
+	assert.Empty(t, svc.violations)
+	//а разные воркеры работают параллельно
+	assert.True(t, svc.overlapped, "workers never processed messages concurrently")
+
+	want := make([]int64, perPartition)
+	for off := range want {
+		want[off] = int64(off)
+	}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for p := 0; p < partitions; p++ {
+		assert.Equal(t, want, svc.seen[p], "processing order of partition %d", p)
+		assert.Equal(t, want, broker.commitLog[p], "commit order of partition %d", p)
+	}
+}
+
+type recordingDLQ struct {
+	mu      sync.Mutex
+	topics  []string
+	headers []map[string]string
+}
+
+func (d *recordingDLQ) SendMessage(_ context.Context, topic string, _, _ []byte, headers map[string]string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.topics = append(d.topics, topic)
+	d.headers = append(d.headers, headers)
+	return nil
+}
+
+func TestConsumer_ValidationErrorsInDLQHeaders(t *testing.T) {
+	order := validOrder("bad-order")
+	order.Items[0].NmID = 0
+	order.Payment.Amount = 1800
+	value, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	dlq := &recordingDLQ{}
+	c := newTestConsumer(t, nil, &slowService{processed: map[string]int{}}, 1)
+	c.dlqProducer = dlq
+
+	err = c.processMessage(context.Background(), kafka.Message{Topic: "orders", Key: []byte("bad-order"), Value: value})
+	require.NoError(t, err)
+	require.Len(t, dlq.headers, 1)
+
+	headers := dlq.headers[0]
+	assert.Equal(t, "validation_failed", headers[HeaderErrorReason])
+
+	var verrs validator.ValidationErrors
+	require.NoError(t, json.Unmarshal([]byte(headers[HeaderValidationErrors]), &verrs))
+	assert.Equal(t, validator.ValidationErrors{
+		{Field: "items[0].nm_id", Code: validator.CodeMustBePositive, Message: "must be > 0"},
+		{
+			Field:   "payment.amount",
+			Code:    validator.CodeInconsistentTotal,
+			Message: "must equal goods_total + delivery_cost + custom_fee (got 1800, want 1817)",
+		},
+	}, verrs)
+}
+
+func TestConsumer_InvalidStatusEventInDLQHeaders(t *testing.T) {
+	dlq := &recordingDLQ{}
+	c := newTestConsumer(t, nil, &slowService{processed: map[string]int{}}, 1)
+	c.dlqProducer = dlq
+
+	err := c.processMessage(context.Background(), kafka.Message{
+		Topic:   "orders",
+		Value:   []byte(`{"order_uid": "uid", "status": "lost"}`),
+		Headers: []kafka.Header{{Key: HeaderEventType, Value: []byte(EventOrderStatus)}},
+	})
+	require.NoError(t, err)
+	require.Len(t, dlq.headers, 1)
+	assert.JSONEq(t, `[{"field":"status","code":"invalid_value","message":"is invalid"}]`,
+		dlq.headers[0][HeaderValidationErrors])
+}
@@ -0,0 +1,31 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Running - крутится ли цикл чтения консьюмера
+func (c *Consumer) Running() bool {
+	return c.running.Load()
+}
+
+// PingBrokers - проверяет, что хотя бы один брокер из списка принимает соединения
+func PingBrokers(ctx context.Context, brokers []string) error {
+	const op = "kafka.PingBrokers"
+
+	var errs []error
+	for _, broker := range brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	return fmt.Errorf("%s: no broker available: %w", op, errors.Join(errs...))
+}
@@ -4,11 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"order-service/internal/metrics"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
+// Delivery - куда записано сообщение
+type Delivery struct {
+	Topic     string
+	Partition int
+	Offset    int64
+}
+
 type Producer struct {
 	writer  *kafka.Writer
 	logger  *slog.Logger
@@ -16,10 +24,23 @@ type Producer struct {
 }
 
 func NewProducer(brokers []string, timeout time.Duration, log *slog.Logger) *Producer {
+	//партиция выбирается по хешу ключа: сообщения одного order_uid (заказ, его статусы, события outbox)
+	//попадают в одну партицию и читаются в порядке записи
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(brokers...),
-		Balancer:     &kafka.LeastBytes{},
+		Balancer:     &kafka.Hash{},
 		RequiredAcks: kafka.RequireAll,
+		//writer копирует сообщения в пачки, поэтому партиция и оффсет возвращаются через WriterData
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				return
+			}
+			for _, m := range messages {
+				if d, ok := m.WriterData.(*Delivery); ok {
+					*d = Delivery{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset}
+				}
+			}
+		},
 	}
 
 	return &Producer{
@@ -31,27 +52,30 @@ func NewProducer(brokers []string, timeout time.Duration, log *slog.Logger) *Pro
 
 // SendMessage - единый метод для отправки сообщений в любой топик.
 func (p *Producer) SendMessage(ctx context.Context, topic string, key, value []byte, headers map[string]string) error {
+	_, err := p.SendMessageWithDelivery(ctx, topic, key, value, headers)
+	return err
+}
+
+// SendMessageWithDelivery - то же, что SendMessage, но возвращает партицию и оффсет записанного сообщения
+func (p *Producer) SendMessageWithDelivery(ctx context.Context, topic string, key, value []byte, headers map[string]string) (Delivery, error) {
 	const op = "kafka.Producer.SendMessage"
 
 	if p.writer == nil {
 		if p.logger != nil {
 			p.logger.Warn("kafka writer is nil")
 		}
-		return fmt.Errorf("writer is nil")
-	}
-
-	kafkaHeaders := make([]kafka.Header, 0, len(headers))
-	for k, v := range headers {
-		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: k, Value: []byte(v)})
+		return Delivery{}, fmt.Errorf("writer is nil")
 	}
 
 	msg := kafka.Message{
 		Topic:   topic,
 		Key:     key,
 		Value:   value,
-		Headers: kafkaHeaders,
+		Headers: kafkaHeaders(headers),
 		Time:    time.Now(),
 	}
+	var delivery Delivery
+	msg.WriterData = &delivery
 
 	log := p.logger.With(
 		slog.String("op", op),
@@ -71,16 +95,93 @@ func (p *Producer) SendMessage(ctx context.Context, topic string, key, value []b
 	}
 	defer cancel()
 
+	start := time.Now()
 	err := p.writer.WriteMessages(ctx2, msg)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.KafkaProducerWriteDuration.WithLabelValues(topic, result).Observe(time.Since(start).Seconds())
 	if err != nil {
 		log.Error("Failed to write message to Kafka", slog.Any("error", err))
+		return Delivery{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Debug("Message sent successfully",
+		slog.Int("partition", delivery.Partition),
+		slog.Int64("offset", delivery.Offset),
+	)
+	return delivery, nil
+}
+
+// Message - сообщение для пакетной отправки через SendMessages
+type Message struct {
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// SendMessages - отправляет пачку сообщений в топик одной записью: writer ждёт BatchTimeout один раз на пачку,
+// а не на каждое сообщение. Ошибка означает, что часть сообщений могла быть записана
+func (p *Producer) SendMessages(ctx context.Context, topic string, messages []Message) error {
+	const op = "kafka.Producer.SendMessages"
+
+	if p.writer == nil {
+		if p.logger != nil {
+			p.logger.Warn("kafka writer is nil")
+		}
+		return fmt.Errorf("writer is nil")
+	}
+
+	now := time.Now()
+	msgs := make([]kafka.Message, len(messages))
+	for i, m := range messages {
+		msgs[i] = kafka.Message{
+			Topic:   topic,
+			Key:     m.Key,
+			Value:   m.Value,
+			Headers: kafkaHeaders(m.Headers),
+			Time:    now,
+		}
+	}
+
+	ctx2 := ctx
+	var cancel context.CancelFunc = func() {}
+	if p.timeout > 0 {
+		if _, has := ctx.Deadline(); !has {
+			ctx2, cancel = context.WithTimeout(ctx, p.timeout)
+		}
+	}
+	defer cancel()
+
+	start := time.Now()
+	err := p.writer.WriteMessages(ctx2, msgs...)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.KafkaProducerWriteDuration.WithLabelValues(topic, result).Observe(time.Since(start).Seconds())
+	if err != nil {
+		p.logger.Error("Failed to write messages to Kafka",
+			slog.String("op", op),
+			slog.String("topic", topic),
+			slog.Int("count", len(msgs)),
+			slog.Any("error", err),
+		)
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Debug("Message sent successfully")
 	return nil
 }
 
+func kafkaHeaders(headers map[string]string) []kafka.Header {
+	kafkaHeaders := make([]kafka.Header, 0, len(headers))
+	for k, v := range headers {
+		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return kafkaHeaders
+}
+
 func (p *Producer) Close() error {
 	if p.writer != nil {
 		p.logger.Info("Closing Kafka producer writer")
@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProducerPartitionsByKey(t *testing.T) {
+	p := NewProducer([]string{"localhost:9092"}, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	balancer := p.writer.Balancer
+	partitions := []int{0, 1, 2, 3, 4, 5}
+
+	used := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("order-%d", i))
+		order := balancer.Balance(kafka.Message{Key: key, Value: []byte(`{"order_uid": "x", "items": [1, 2, 3]}`)}, partitions...)
+		//событие статуса с тем же ключом и другим размером должно попасть туда же, куда заказ
+		status := balancer.Balance(kafka.Message{Key: key, Value: []byte(`{"status": "paid"}`)}, partitions...)
+		require.Equal(t, order, status, "messages with key %s must share a partition", key)
+		used[order] = true
+	}
+
+	assert.Greater(t, len(used), 1, "different keys must spread over partitions")
+}
+
+func TestProducerKeepsOrderEventsInOnePartition(t *testing.T) {
+	p := NewProducer([]string{"localhost:9092"}, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	partitions := []int{0, 1, 2, 3, 4, 5, 6, 7}
+
+	//релей outbox отправляет версии заказа вперемешку с событиями других заказов
+	var first int
+	for version := 1; version <= 20; version++ {
+		other := kafka.Message{Key: []byte(fmt.Sprintf("other-%d", version)), Value: make([]byte, 1000*version)}
+		p.writer.Balancer.Balance(other, partitions...)
+
+		event := kafka.Message{
+			Key:   []byte("uid-1"),
+			Value: []byte(fmt.Sprintf(`{"event_id": "order.saved:uid-1:%d", "version": %d}`, version, version)),
+		}
+		partition := p.writer.Balancer.Balance(event, partitions...)
+		if version == 1 {
+			first = partition
+		}
+		require.Equal(t, first, partition, "version %d of order must stay in the partition of version 1", version)
+	}
+}
@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовки, которыми сообщение помечается при пересылке в retry-топики и DLQ
+const (
+	HeaderRetryAttempt   = "retry_attempt"
+	HeaderErrorReason    = "error_reason"
+	HeaderErrorDetails   = "error_details"
+	HeaderOriginalTopic  = "original_topic"
+	HeaderOriginalOffset = "original_offset"
+	HeaderEventType      = "event_type"
+	//JSON-массив ошибок валидации по полям: [{"field": ..., "code": ..., "message": ...}]
+	HeaderValidationErrors = "validation_errors"
+)
+
+// EventOrderStatus - значение HeaderEventType у событий смены статуса.
+// Сообщения без этого заголовка считаются новыми заказами
+const EventOrderStatus = "order_status"
+
+// RetryStage - ступень лестницы ретраев: топик и задержка перед повторной обработкой
+type RetryStage struct {
+	Topic string
+	Delay time.Duration
+}
+
+// RetryPolicy - политика повторной обработки сообщений при временных ошибках.
+// Сначала сообщение обрабатывается повторно внутри процесса с экспоненциальным backoff,
+// затем пересылается по ступеням Stages, а после последней ступени - в DLQ
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Stages         []RetryStage
+}
+
+// NewRetryPolicy - собирает политику из конфига. Ступени задаются строками вида "topic=delay",
+// например "orders_retry_30s=30s"
+func NewRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration, stages []string) (RetryPolicy, error) {
+	const op = "kafka.NewRetryPolicy"
+
+	policy := RetryPolicy{
+		MaxAttempts:    max(maxAttempts, 1),
+		InitialBackoff: initialBackoff,
+		MaxBackoff:     maxBackoff,
+	}
+
+	for _, s := range stages {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		topic, delay, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(topic) == "" {
+			return RetryPolicy{}, fmt.Errorf("%s: stage %q must be in form topic=delay", op, s)
+		}
+
+		d, err := time.ParseDuration(strings.TrimSpace(delay))
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("%s: stage %q: %w", op, s, err)
+		}
+
+		policy.Stages = append(policy.Stages, RetryStage{Topic: strings.TrimSpace(topic), Delay: d})
+	}
+
+	return policy, nil
+}
+
+// Topics - возвращает топики всех ступеней ретраев
+func (p RetryPolicy) Topics() []string {
+	topics := make([]string, 0, len(p.Stages))
+	for _, s := range p.Stages {
+		topics = append(topics, s.Topic)
+	}
+	return topics
+}
+
+// stageOf - возвращает номер ступени для топика (0 - исходный топик) и задержку ступени
+func (p RetryPolicy) stageOf(topic string) (int, time.Duration) {
+	for i, s := range p.Stages {
+		if s.Topic == topic {
+			return i + 1, s.Delay
+		}
+	}
+	return 0, 0
+}
+
+// backoff - задержка перед попыткой attempt (с нуля): экспонента от InitialBackoff,
+// ограниченная MaxBackoff, со случайным разбросом в пределах [d/2, d]
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	d := p.InitialBackoff
+	for i := 0; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// sleepCtx - ждёт d или отмены контекста
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func headerValue(msg kafka.Message, key string) (string, bool) {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+// retryAttempt - сколько раз сообщение уже пересылалось по лестнице ретраев
+func retryAttempt(msg kafka.Message) int {
+	v, ok := headerValue(msg, HeaderRetryAttempt)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// isStatusEvent - является ли сообщение событием смены статуса
+func isStatusEvent(msg kafka.Message) bool {
+	eventType, _ := headerValue(msg, HeaderEventType)
+	return eventType == EventOrderStatus
+}
+
+// originHeaders - заголовки с исходным топиком, offset'ом и типом сообщения.
+// Для сообщений из retry-топиков берутся значения, сохранённые при первой пересылке
+func originHeaders(msg kafka.Message) map[string]string {
+	topic, ok := headerValue(msg, HeaderOriginalTopic)
+	if !ok {
+		topic = msg.Topic
+	}
+	offset, ok := headerValue(msg, HeaderOriginalOffset)
+	if !ok {
+		offset = strconv.FormatInt(msg.Offset, 10)
+	}
+
+	headers := map[string]string{
+		HeaderOriginalTopic:  topic,
+		HeaderOriginalOffset: offset,
+	}
+	if eventType, ok := headerValue(msg, HeaderEventType); ok {
+		headers[HeaderEventType] = eventType
+	}
+	if attempt := retryAttempt(msg); attempt > 0 {
+		headers[HeaderRetryAttempt] = strconv.Itoa(attempt)
+	}
+	return headers
+}
@@ -0,0 +1,257 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/service"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetryPolicy_ParsesStages(t *testing.T) {
+	p, err := NewRetryPolicy(3, 100*time.Millisecond, time.Second,
+		[]string{"orders_retry_30s=30s", " orders_retry_5m = 5m ", ""})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, p.MaxAttempts)
+	assert.Equal(t, []RetryStage{
+		{Topic: "orders_retry_30s", Delay: 30 * time.Second},
+		{Topic: "orders_retry_5m", Delay: 5 * time.Minute},
+	}, p.Stages)
+	assert.Equal(t, []string{"orders_retry_30s", "orders_retry_5m"}, p.Topics())
+}
+
+func TestNewRetryPolicy_InvalidStage(t *testing.T) {
+	_, err := NewRetryPolicy(3, 0, 0, []string{"orders_retry_30s"})
+	assert.Error(t, err)
+
+	_, err = NewRetryPolicy(3, 0, 0, []string{"orders_retry=soon"})
+	assert.Error(t, err)
+}
+
+func TestNewRetryPolicy_AtLeastOneAttempt(t *testing.T) {
+	p, err := NewRetryPolicy(0, 0, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.MaxAttempts)
+}
+
+func TestRetryPolicy_StageOf(t *testing.T) {
+	p, err := NewRetryPolicy(1, 0, 0, []string{"r1=30s", "r2=5m"})
+	require.NoError(t, err)
+
+	stage, delay := p.stageOf("orders")
+	assert.Equal(t, 0, stage)
+	assert.Zero(t, delay)
+
+	stage, delay = p.stageOf("r2")
+	assert.Equal(t, 2, stage)
+	assert.Equal(t, 5*time.Minute, delay)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 0, max: 100 * time.Millisecond},
+		{attempt: 1, max: 200 * time.Millisecond},
+		{attempt: 3, max: 800 * time.Millisecond},
+		{attempt: 10, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			d := p.backoff(tt.attempt)
+			assert.GreaterOrEqual(t, d, tt.max/2)
+			assert.LessOrEqual(t, d, tt.max)
+		}
+	}
+
+	assert.Zero(t, RetryPolicy{}.backoff(5))
+}
+
+func TestOriginHeaders(t *testing.T) {
+	fresh := kafka.Message{Topic: "orders", Offset: 42}
+	assert.Equal(t, map[string]string{
+		HeaderOriginalTopic:  "orders",
+		HeaderOriginalOffset: "42",
+	}, originHeaders(fresh))
+
+	retried := kafka.Message{
+		Topic:  "orders_retry_30s",
+		Offset: 7,
+		Headers: []kafka.Header{
+			{Key: HeaderOriginalTopic, Value: []byte("orders")},
+			{Key: HeaderOriginalOffset, Value: []byte("42")},
+			{Key: HeaderRetryAttempt, Value: []byte("1")},
+		},
+	}
+	assert.Equal(t, map[string]string{
+		HeaderOriginalTopic:  "orders",
+		HeaderOriginalOffset: "42",
+		HeaderRetryAttempt:   "1",
+	}, originHeaders(retried))
+	assert.Equal(t, 1, retryAttempt(retried))
+}
+
+// failingService - первые failures вызовов обработки завершаются err
+type failingService struct {
+	slowService
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *failingService) fail() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failures {
+		return s.err
+	}
+	return nil
+}
+
+func (s *failingService) ProcessNewOrder(context.Context, *models.Order) error { return s.fail() }
+
+func (s *failingService) UpdateOrderStatus(context.Context, *models.StatusEvent) error {
+	return s.fail()
+}
+
+// newRetryConsumer - консьюмер ступени stage лестницы orders -> orders_retry_1 -> orders_retry_2 -> DLQ
+func newRetryConsumer(t *testing.T, svc OrderService, stage int) (*Consumer, *recordingDLQ) {
+	dlq := &recordingDLQ{}
+	c := newTestConsumer(t, nil, svc, 0)
+	c.dlqProducer = dlq
+	c.retry.Stages = []RetryStage{{Topic: "orders_retry_1", Delay: time.Second}, {Topic: "orders_retry_2", Delay: time.Minute}}
+	c.stage = stage
+	return c, dlq
+}
+
+func orderMessage(t *testing.T, topic string, headers ...kafka.Header) kafka.Message {
+	value, err := json.Marshal(validOrder("uid-1"))
+	require.NoError(t, err)
+	return kafka.Message{Topic: topic, Offset: 42, Key: []byte("uid-1"), Value: value, Headers: headers}
+}
+
+func TestConsumer_DeliverRetriesInProcess(t *testing.T) {
+	svc := &failingService{failures: 2, err: errors.New("db unavailable")}
+	c, dlq := newRetryConsumer(t, svc, 0)
+
+	require.NoError(t, c.deliver(context.Background(), orderMessage(t, "orders")))
+
+	//третья попытка удалась - дальше сообщение не пересылается
+	assert.Equal(t, 3, svc.calls)
+	assert.Empty(t, dlq.topics)
+}
+
+func TestConsumer_DeliverForwardsToNextStage(t *testing.T) {
+	svc := &failingService{failures: 100, err: errors.New("db unavailable")}
+	c, dlq := newRetryConsumer(t, svc, 0)
+
+	require.NoError(t, c.deliver(context.Background(), orderMessage(t, "orders")))
+
+	assert.Equal(t, c.retry.MaxAttempts, svc.calls)
+	require.Equal(t, []string{"orders_retry_1"}, dlq.topics)
+	assert.Equal(t, map[string]string{
+		HeaderOriginalTopic:  "orders",
+		HeaderOriginalOffset: "42",
+		HeaderRetryAttempt:   "1",
+		HeaderErrorDetails:   "kafka.processMessage: failed to process order: db unavailable",
+	}, dlq.headers[0])
+
+	//консьюмер первой ступени пересылает на вторую и увеличивает счётчик, сохраняя исходный топик
+	c, dlq = newRetryConsumer(t, svc, 1)
+	msg := orderMessage(t, "orders_retry_1",
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte("orders")},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte("42")},
+		kafka.Header{Key: HeaderRetryAttempt, Value: []byte("1")},
+	)
+	require.NoError(t, c.deliver(context.Background(), msg))
+
+	require.Equal(t, []string{"orders_retry_2"}, dlq.topics)
+	assert.Equal(t, "2", dlq.headers[0][HeaderRetryAttempt])
+	assert.Equal(t, "orders", dlq.headers[0][HeaderOriginalTopic])
+	assert.Empty(t, dlq.headers[0][HeaderErrorReason])
+}
+
+func TestConsumer_DeliverRetriesExhausted(t *testing.T) {
+	svc := &failingService{failures: 100, err: errors.New("db unavailable")}
+	c, dlq := newRetryConsumer(t, svc, 2)
+	msg := orderMessage(t, "orders_retry_2",
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte("orders")},
+		kafka.Header{Key: HeaderRetryAttempt, Value: []byte("2")},
+	)
+
+	require.NoError(t, c.deliver(context.Background(), msg))
+
+	//после последней ступени сообщение уходит в DLQ
+	require.Equal(t, []string{"orders_dlq"}, dlq.topics)
+	assert.Equal(t, "retries_exhausted", dlq.headers[0][HeaderErrorReason])
+	assert.Equal(t, "3", dlq.headers[0][HeaderRetryAttempt])
+	assert.Equal(t, "orders", dlq.headers[0][HeaderOriginalTopic])
+}
+
+func TestConsumer_DeliverPermanentErrorSkipsRetries(t *testing.T) {
+	statusHeader := kafka.Header{Key: HeaderEventType, Value: []byte(EventOrderStatus)}
+	tests := []struct {
+		name   string
+		err    error
+		msg    func(t *testing.T) kafka.Message
+		reason string
+		calls  int
+	}{
+		{
+			name:   "changed order",
+			err:    repository.ErrOrderChanged,
+			msg:    func(t *testing.T) kafka.Message { return orderMessage(t, "orders") },
+			reason: "order_changed",
+			calls:  1,
+		},
+		{
+			name: "invalid status transition",
+			err:  service.ErrInvalidTransition,
+			msg: func(*testing.T) kafka.Message {
+				return kafka.Message{
+					Topic:   "orders",
+					Key:     []byte("uid-1"),
+					Value:   []byte(`{"order_uid": "uid-1", "status": "paid", "changed_at": "2025-01-01T12:00:00Z"}`),
+					Headers: []kafka.Header{statusHeader},
+				}
+			},
+			reason: "invalid_status_transition",
+			calls:  1,
+		},
+		{
+			name: "invalid json",
+			msg: func(*testing.T) kafka.Message {
+				return kafka.Message{Topic: "orders", Key: []byte("uid-1"), Value: []byte(`{"order_uid":`)}
+			},
+			reason: "json_unmarshal_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &failingService{failures: 100, err: tt.err}
+			c, dlq := newRetryConsumer(t, svc, 0)
+
+			require.NoError(t, c.deliver(context.Background(), tt.msg(t)))
+
+			//ретраи не исправят сообщение: одна попытка и сразу DLQ, минуя retry-топики
+			assert.Equal(t, tt.calls, svc.calls)
+			require.Equal(t, []string{"orders_dlq"}, dlq.topics)
+			assert.Equal(t, tt.reason, dlq.headers[0][HeaderErrorReason])
+		})
+	}
+}
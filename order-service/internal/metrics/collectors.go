@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"order-service/internal/cache"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+// CacheStatser - кеш, который отдаёт свои счётчики
+type CacheStatser interface {
+	Stats() cache.Stats
+}
+
+type cacheCollector struct {
+	cache       CacheStatser
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	evictions   *prometheus.Desc
+	expirations *prometheus.Desc
+	size        *prometheus.Desc
+	capacity    *prometheus.Desc
+	bytes       *prometheus.Desc
+	maxBytes    *prometheus.Desc
+}
+
+// NewCacheCollector - коллектор счётчиков кеша заказов
+func NewCacheCollector(c CacheStatser) prometheus.Collector {
+	return &cacheCollector{
+		cache:       c,
+		hits:        desc("cache_hits_total", "Number of cache hits."),
+		misses:      desc("cache_misses_total", "Number of cache misses."),
+		evictions:   desc("cache_evictions_total", "Number of orders evicted from the cache."),
+		expirations: desc("cache_expirations_total", "Number of orders removed from the cache after their TTL."),
+		size:        desc("cache_size", "Number of orders in the cache."),
+		capacity:    desc("cache_capacity", "Maximum number of orders in the cache."),
+		bytes:       desc("cache_bytes", "Estimated memory used by cached orders in bytes."),
+		maxBytes:    desc("cache_max_bytes", "Cache memory budget in bytes, 0 if unbounded."),
+	}
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.expirations
+	ch <- c.size
+	ch <- c.capacity
+	ch <- c.bytes
+	ch <- c.maxBytes
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(s.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(s.Expirations))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(s.Size))
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(s.Capacity))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(s.Bytes))
+	ch <- prometheus.MustNewConstMetric(c.maxBytes, prometheus.GaugeValue, float64(s.MaxBytes))
+}
+
+type poolCollector struct {
+	pool            *pgxpool.Pool
+	acquired        *prometheus.Desc
+	idle            *prometheus.Desc
+	total           *prometheus.Desc
+	max             *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceled        *prometheus.Desc
+}
+
+// NewPoolCollector - коллектор статистики пула соединений с Postgres
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &poolCollector{
+		pool:            pool,
+		acquired:        desc("pgxpool_acquired_conns", "Number of connections currently in use."),
+		idle:            desc("pgxpool_idle_conns", "Number of idle connections."),
+		total:           desc("pgxpool_total_conns", "Total number of connections in the pool."),
+		max:             desc("pgxpool_max_conns", "Maximum size of the pool."),
+		acquires:        desc("pgxpool_acquires_total", "Number of successful connection acquires."),
+		acquireDuration: desc("pgxpool_acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquires:   desc("pgxpool_empty_acquires_total", "Number of acquires that had to wait for a connection."),
+		canceled:        desc("pgxpool_canceled_acquires_total", "Number of acquires canceled by context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquires
+	ch <- c.canceled
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
+
+// ReaderStatser - консьюмер, который отдаёт статистику своего kafka.Reader
+type ReaderStatser interface {
+	ReaderStats() kafka.ReaderStats
+}
+
+type readerCollector struct {
+	readers  []ReaderStatser
+	lag      *prometheus.Desc
+	queueLen *prometheus.Desc
+	queueCap *prometheus.Desc
+}
+
+// NewReaderCollector - коллектор статистики kafka.Reader'ов консьюмеров.
+// Счётчики kafka.ReaderStats сбрасываются при каждом чтении, поэтому отдаются только мгновенные значения
+func NewReaderCollector(readers ...ReaderStatser) prometheus.Collector {
+	labels := []string{"topic", "partition"}
+	return &readerCollector{
+		readers:  readers,
+		lag:      prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "kafka_reader_lag"), "Consumer lag reported by kafka.Reader.", labels, nil),
+		queueLen: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "kafka_reader_queue_length"), "Messages fetched but not yet read.", labels, nil),
+		queueCap: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "kafka_reader_queue_capacity"), "Capacity of the reader fetch queue.", labels, nil),
+	}
+}
+
+func (c *readerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lag
+	ch <- c.queueLen
+	ch <- c.queueCap
+}
+
+func (c *readerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, r := range c.readers {
+		s := r.ReaderStats()
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, float64(s.Lag), s.Topic, s.Partition)
+		ch <- prometheus.MustNewConstMetric(c.queueLen, prometheus.GaugeValue, float64(s.QueueLength), s.Topic, s.Partition)
+		ch <- prometheus.MustNewConstMetric(c.queueCap, prometheus.GaugeValue, float64(s.QueueCapacity), s.Topic, s.Partition)
+	}
+}
+
+func desc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "order_service"
+
+// Метрики, которые пишутся по месту событий. Метрики, которые снимаются со снимков состояния
+// (кеш, пул соединений, kafka.Reader), отдаются коллекторами из collectors.go
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method", "route"})
+
+	KafkaMessagesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_processed_total",
+		Help:      "Number of consumed messages that reached a final state and were committed.",
+	}, []string{"topic"})
+
+	KafkaMessagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_failed_total",
+		Help:      "Number of failed message processing attempts.",
+	}, []string{"topic"})
+
+	KafkaMessagesRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_retried_total",
+		Help:      "Number of messages forwarded to a retry topic.",
+	}, []string{"topic"})
+
+	KafkaMessagesDLQ = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_dlq_total",
+		Help:      "Number of messages sent to the DLQ by reason.",
+	}, []string{"reason"})
+
+	KafkaProducerWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_producer_write_duration_seconds",
+		Help:      "Kafka producer write latency by topic and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic", "result"})
+
+	FeedSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "feed_subscribers",
+		Help:      "Number of connected live order feed clients (SSE and WebSocket).",
+	})
+
+	FeedEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feed_events_dropped_total",
+		Help:      "Number of orders not delivered to slow live feed clients because their buffer was full.",
+	})
+
+	OutboxEventsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_published_total",
+		Help:      "Number of outbox events published to Kafka by the relay.",
+	})
+
+	OutboxRelayErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_relay_errors_total",
+		Help:      "Number of failed outbox relay attempts; events are retried on the next poll.",
+	})
+)
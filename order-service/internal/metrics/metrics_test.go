@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"order-service/internal/cache"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestGinMiddleware_LabelsByRoute(t *testing.T) {
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/order/:order_uid", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	before := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "/order/:order_uid", "404"))
+
+	for _, uid := range []string{"a", "b"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/order/"+uid, nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	after := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "/order/:order_uid", "404"))
+	assert.Equal(t, 2.0, after-before)
+}
+
+type stubCache cache.Stats
+
+func (s stubCache) Stats() cache.Stats { return cache.Stats(s) }
+
+func TestCacheCollector(t *testing.T) {
+	c := NewCacheCollector(stubCache{Hits: 3, Misses: 2, Evictions: 1, Expirations: 4, Size: 5, Capacity: 10, Bytes: 2048, MaxBytes: 4096})
+
+	expected := `
+# HELP order_service_cache_hits_total Number of cache hits.
+# TYPE order_service_cache_hits_total counter
+order_service_cache_hits_total 3
+# HELP order_service_cache_misses_total Number of cache misses.
+# TYPE order_service_cache_misses_total counter
+order_service_cache_misses_total 2
+# HELP order_service_cache_evictions_total Number of orders evicted from the cache.
+# TYPE order_service_cache_evictions_total counter
+order_service_cache_evictions_total 1
+# HELP order_service_cache_expirations_total Number of orders removed from the cache after their TTL.
+# TYPE order_service_cache_expirations_total counter
+order_service_cache_expirations_total 4
+# HELP order_service_cache_size Number of orders in the cache.
+# TYPE order_service_cache_size gauge
+order_service_cache_size 5
+# HELP order_service_cache_capacity Maximum number of orders in the cache.
+# TYPE order_service_cache_capacity gauge
+order_service_cache_capacity 10
+# HELP order_service_cache_bytes Estimated memory used by cached orders in bytes.
+# TYPE order_service_cache_bytes gauge
+order_service_cache_bytes 2048
+# HELP order_service_cache_max_bytes Cache memory budget in bytes, 0 if unbounded.
+# TYPE order_service_cache_max_bytes gauge
+order_service_cache_max_bytes 4096
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected)))
+}
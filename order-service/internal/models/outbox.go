@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// EventOrderSaved - тип события о сохранённом заказе: новый заказ или новая версия заказа
+const EventOrderSaved = "order.saved"
+
+// OrderSavedEvent - тело события order.saved.
+// EventID - ключ дедупликации: при повторной доставке приходит то же значение, и потребитель может пропустить событие
+type OrderSavedEvent struct {
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Version    int       `json:"version"`
+	Order      *Order    `json:"order"`
+}
+
+// OrderSavedEventID - ключ дедупликации события: одна версия заказа сохраняется ровно один раз
+func OrderSavedEventID(orderUID string, version int) string {
+	return EventOrderSaved + ":" + orderUID + ":" + strconv.Itoa(version)
+}
+
+// OutboxEvent - неотправленное событие из таблицы outbox
+type OutboxEvent struct {
+	ID        int64
+	EventID   string
+	EventType string
+	// ключ сообщения в Kafka (order_uid). Продюсер выбирает партицию по хешу ключа,
+	// поэтому версии одного заказа попадают в одну партицию и читаются по порядку
+	Key       string
+	Payload   []byte
+	CreatedAt time.Time
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderFilter - параметры поиска заказов. Пустые поля не участвуют в фильтрации.
+// Результат отсортирован по date_created от новых к старым
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	Phone           string
+	Email           string
+	Transaction     string
+	DeliveryService string
+	CreatedFrom     time.Time //включительно
+	CreatedTo       time.Time //не включительно
+	NmID            int
+	Brand           string
+
+	Limit int
+	After *Cursor
+}
+
+// Cursor - позиция последнего заказа на странице. order_uid разрешает совпадения date_created
+type Cursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// Encode - непрозрачная строка курсора для передачи клиенту
+func (c Cursor) Encode() string {
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + "|" + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor - разбирает строку, полученную из Cursor.Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	date, uid, ok := strings.Cut(string(raw), "|")
+	if !ok || uid == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{DateCreated: t, OrderUID: uid}, nil
+}
+
+// OrderPage - страница результатов поиска. NextCursor пустой на последней странице
+type OrderPage struct {
+	Orders     []*Order `json:"orders"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
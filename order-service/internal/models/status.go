@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// OrderStatus - статус заказа в его жизненном цикле
+type OrderStatus string
+
+const (
+	StatusCreated   OrderStatus = "created"
+	StatusPaid      OrderStatus = "paid"
+	StatusAssembled OrderStatus = "assembled"
+	StatusShipped   OrderStatus = "shipped"
+	StatusDelivered OrderStatus = "delivered"
+	StatusCancelled OrderStatus = "cancelled"
+	StatusReturned  OrderStatus = "returned"
+)
+
+// Valid - является ли значение известным статусом
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case StatusCreated, StatusPaid, StatusAssembled, StatusShipped,
+		StatusDelivered, StatusCancelled, StatusReturned:
+		return true
+	}
+	return false
+}
+
+// StatusChange - запись в истории статусов заказа. From пустой у первой записи
+type StatusChange struct {
+	From      OrderStatus `json:"from,omitempty"`
+	To        OrderStatus `json:"to"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// StatusEvent - событие смены статуса, приходящее из Kafka
+type StatusEvent struct {
+	OrderUID  string      `json:"order_uid"`
+	Status    OrderStatus `json:"status"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Reason    string      `json:"reason,omitempty"`
+}
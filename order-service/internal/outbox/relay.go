@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"order-service/internal/kafka"
+	"order-service/internal/metrics"
+	"order-service/internal/models"
+	"time"
+)
+
+// заголовки сообщения; event_id совпадает с полем event_id в теле
+const (
+	HeaderEventID   = "event_id"
+	HeaderEventType = "event_type"
+)
+
+// как часто удалять старые отправленные события
+const cleanupInterval = 10 * time.Minute
+
+// Store - таблица outbox
+type Store interface {
+	RelayOutbox(ctx context.Context, limit int, publish func(context.Context, []models.OutboxEvent) error) (int, error)
+	DeleteSentOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Publisher interface {
+	SendMessages(ctx context.Context, topic string, messages []kafka.Message) error
+}
+
+// Relay - переносит события из outbox в Kafka.
+// Гарантия at-least-once: событие помечается отправленным только после записи в Kafka,
+// поэтому после сбоя оно может прийти повторно с тем же event_id
+type Relay struct {
+	store     Store
+	publisher Publisher
+	topic     string
+	interval  time.Duration
+	batchSize int
+	//сколько хранить отправленные события; 0 - не удалять
+	retention time.Duration
+	log       *slog.Logger
+}
+
+func NewRelay(store Store, publisher Publisher, topic string, interval time.Duration, batchSize int, retention time.Duration, log *slog.Logger) *Relay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		topic:     topic,
+		interval:  interval,
+		batchSize: batchSize,
+		retention: retention,
+		log:       log,
+	}
+}
+
+// Run - раз в interval отправляет все накопившиеся события, пока не отменён ctx.
+// Пачка, прерванная отменой, не помечается отправленной и уйдёт после перезапуска
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		r.drain(ctx)
+
+		if r.retention > 0 && time.Since(lastCleanup) >= cleanupInterval {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain - отправляет пачки, пока в outbox есть неотправленные события
+func (r *Relay) drain(ctx context.Context) {
+	const op = "outbox.Relay.drain"
+
+	for ctx.Err() == nil {
+		n, err := r.store.RelayOutbox(ctx, r.batchSize, r.publish)
+		if err != nil {
+			if ctx.Err() == nil {
+				metrics.OutboxRelayErrors.Inc()
+				r.log.Error("failed to relay outbox events",
+					slog.String("op", op),
+					slog.String("topic", r.topic),
+					slog.Any("error", err),
+				)
+			}
+			return
+		}
+		if n < r.batchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, events []models.OutboxEvent) error {
+	messages := make([]kafka.Message, len(events))
+	for i, event := range events {
+		messages[i] = kafka.Message{
+			Key:   []byte(event.Key),
+			Value: event.Payload,
+			Headers: map[string]string{
+				HeaderEventID:   event.EventID,
+				HeaderEventType: event.EventType,
+			},
+		}
+	}
+
+	if err := r.publisher.SendMessages(ctx, r.topic, messages); err != nil {
+		return err
+	}
+
+	metrics.OutboxEventsPublished.Add(float64(len(events)))
+	return nil
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	const op = "outbox.Relay.cleanup"
+
+	deleted, err := r.store.DeleteSentOutbox(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log.Error("failed to delete sent outbox events",
+				slog.String("op", op),
+				slog.Any("error", err),
+			)
+		}
+		return
+	}
+	if deleted > 0 {
+		r.log.Debug("deleted sent outbox events",
+			slog.String("op", op),
+			slog.Int64("deleted", deleted),
+		)
+	}
+}
@@ -0,0 +1,181 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/kafka"
+	"order-service/internal/models"
+	"order-service/internal/outbox"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// fakeStore - outbox в памяти с той же семантикой, что у PostgresRepository.RelayOutbox
+type fakeStore struct {
+	mu            sync.Mutex
+	events        []models.OutboxEvent
+	sent          map[int64]bool
+	deletedBefore time.Time
+}
+
+func newFakeStore(uids ...string) *fakeStore {
+	s := &fakeStore{sent: make(map[int64]bool)}
+	for i, uid := range uids {
+		s.events = append(s.events, models.OutboxEvent{
+			ID:        int64(i + 1),
+			EventID:   models.OrderSavedEventID(uid, 1),
+			EventType: models.EventOrderSaved,
+			Key:       uid,
+			Payload:   []byte(`{"order_uid":"` + uid + `"}`),
+		})
+	}
+	return s
+}
+
+func (s *fakeStore) RelayOutbox(ctx context.Context, limit int, publish func(context.Context, []models.OutboxEvent) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var batch []models.OutboxEvent
+	for _, e := range s.events {
+		if !s.sent[e.ID] && len(batch) < limit {
+			batch = append(batch, e)
+		}
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(ctx, batch); err != nil {
+		return 0, err
+	}
+	for _, e := range batch {
+		s.sent[e.ID] = true
+	}
+	return len(batch), nil
+}
+
+func (s *fakeStore) DeleteSentOutbox(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deletedBefore = before
+	return 0, nil
+}
+
+func (s *fakeStore) unsent() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events) - len(s.sent)
+}
+
+// recordingPublisher - запоминает отправленные пачки, первые failures вызовов завершаются ошибкой
+type recordingPublisher struct {
+	mu       sync.Mutex
+	failures int
+	topics   []string
+	batches  [][]kafka.Message
+}
+
+func (p *recordingPublisher) SendMessages(_ context.Context, topic string, messages []kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.topics = append(p.topics, topic)
+	p.batches = append(p.batches, messages)
+	return nil
+}
+
+func (p *recordingPublisher) messages() []kafka.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var all []kafka.Message
+	for _, b := range p.batches {
+		all = append(all, b...)
+	}
+	return all
+}
+
+// runRelay - запускает релей и останавливает его в конце теста
+func runRelay(t *testing.T, relay *outbox.Relay) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("relay did not stop")
+		}
+	})
+}
+
+func TestRelay_PublishesInBatches(t *testing.T) {
+	store := newFakeStore("uid-1", "uid-2", "uid-3", "uid-4", "uid-5")
+	publisher := &recordingPublisher{}
+	runRelay(t, outbox.NewRelay(store, publisher, "order_events", time.Hour, 2, 0, testLogger()))
+
+	//всё накопившееся уходит сразу, не дожидаясь следующего интервала
+	require.Eventually(t, func() bool { return store.unsent() == 0 }, time.Second, time.Millisecond)
+
+	publisher.mu.Lock()
+	assert.Len(t, publisher.batches, 3)
+	assert.Equal(t, []string{"order_events", "order_events", "order_events"}, publisher.topics)
+	publisher.mu.Unlock()
+
+	msgs := publisher.messages()
+	require.Len(t, msgs, 5)
+	assert.Equal(t, "uid-1", string(msgs[0].Key))
+	assert.Equal(t, `{"order_uid":"uid-1"}`, string(msgs[0].Value))
+	assert.Equal(t, map[string]string{
+		outbox.HeaderEventID:   "order.saved:uid-1:1",
+		outbox.HeaderEventType: models.EventOrderSaved,
+	}, msgs[0].Headers)
+}
+
+func TestRelay_RetriesAfterPublishError(t *testing.T) {
+	store := newFakeStore("uid-1", "uid-2")
+	publisher := &recordingPublisher{failures: 2}
+	runRelay(t, outbox.NewRelay(store, publisher, "order_events", 10*time.Millisecond, 100, 0, testLogger()))
+
+	//неудачные попытки не помечают события отправленными, они уходят на следующем интервале
+	require.Eventually(t, func() bool { return store.unsent() == 0 }, time.Second, time.Millisecond)
+
+	msgs := publisher.messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "uid-1", string(msgs[0].Key))
+	assert.Equal(t, "uid-2", string(msgs[1].Key))
+}
+
+func TestRelay_Cleanup(t *testing.T) {
+	store := newFakeStore()
+	start := time.Now()
+	runRelay(t, outbox.NewRelay(store, &recordingPublisher{}, "order_events", time.Hour, 100, 24*time.Hour, testLogger()))
+
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return !store.deletedBefore.IsZero()
+	}, time.Second, time.Millisecond)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.WithinDuration(t, start.Add(-24*time.Hour), store.deletedBefore, time.Second)
+}
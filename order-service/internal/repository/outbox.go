@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/models"
+	"time"
+)
+
+// RelayOutbox - берёт до limit самых старых неотправленных событий outbox и передаёт их в publish.
+// Строки заблокированы до конца транзакции, поэтому другие экземпляры сервиса их пропускают (SKIP LOCKED).
+// События помечаются отправленными только после успешного publish; если пометить не удалось,
+// они уйдут повторно - потребители отбрасывают дубли по event_id. Возвращает число отправленных событий
+func (r *PostgresRepository) RelayOutbox(ctx context.Context, limit int, publish func(context.Context, []models.OutboxEvent) error) (int, error) {
+	const op = "PostgresRepository.RelayOutbox"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT id, event_id, event_type, aggregate_id, payload, created_at
+		FROM outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err = rows.Scan(&event.ID, &event.EventID, &event.EventType, &event.Key, &event.Payload, &event.CreatedAt); err != nil {
+			return 0, fmt.Errorf("%s: scan failed: %w", op, err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: rows error: %w", op, err)
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err = publish(ctx, events); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, len(events))
+	for i := range events {
+		ids[i] = events[i].ID
+	}
+	if _, err = tx.Exec(ctx, `UPDATE outbox SET sent_at = NOW() WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("%s: mark sent %w", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: commit %w", op, err)
+	}
+
+	return len(events), nil
+}
+
+// DeleteSentOutbox - удаляет события, отправленные раньше before, чтобы outbox не рос бесконечно
+func (r *PostgresRepository) DeleteSentOutbox(ctx context.Context, before time.Time) (int64, error) {
+	const op = "PostgresRepository.DeleteSentOutbox"
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM outbox WHERE sent_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
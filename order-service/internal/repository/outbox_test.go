@@ -0,0 +1,127 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/models"
+	"order-service/internal/repository"
+)
+
+// relayAll - забирает неотправленные события и возвращает их, помечая отправленными
+func relayAll(ctx context.Context, t *testing.T, repo *repository.PostgresRepository) []models.OutboxEvent {
+	t.Helper()
+	var got []models.OutboxEvent
+	_, err := repo.RelayOutbox(ctx, 100, func(_ context.Context, events []models.OutboxEvent) error {
+		got = events
+		return nil
+	})
+	require.NoError(t, err)
+	return got
+}
+
+func TestPostgresRepository_Outbox(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	order := createSampleOrder("order1", time.Now())
+	require.NoError(t, repo.SaveOrder(ctx, order))
+	require.NoError(t, repo.SaveOrders(ctx, []*models.Order{createSampleOrder("order2", time.Now())}))
+
+	//повтор заказа не пишет второе событие
+	assert.ErrorIs(t, repo.SaveOrder(ctx, order), repository.ErrOrderExists)
+
+	events := relayAll(ctx, t, repo)
+	require.Len(t, events, 2)
+	assert.Equal(t, "order.saved:order1:1", events[0].EventID)
+	assert.Equal(t, models.EventOrderSaved, events[0].EventType)
+	assert.Equal(t, "order1", events[0].Key)
+	assert.Equal(t, "order.saved:order2:1", events[1].EventID)
+
+	var payload models.OrderSavedEvent
+	require.NoError(t, json.Unmarshal(events[0].Payload, &payload))
+	assert.Equal(t, events[0].EventID, payload.EventID)
+	assert.Equal(t, 1, payload.Version)
+	assert.Equal(t, order, payload.Order)
+
+	//отправленные события больше не выдаются
+	assert.Empty(t, relayAll(ctx, t, repo))
+
+	//новая версия заказа - новое событие
+	order.Payment.Amount = 200
+	require.NoError(t, repo.UpsertOrder(ctx, order))
+	events = relayAll(ctx, t, repo)
+	require.Len(t, events, 1)
+	assert.Equal(t, "order.saved:order1:2", events[0].EventID)
+
+	deleted, err := repo.DeleteSentOutbox(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+}
+
+func TestPostgresRepository_OutboxDisabled(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	repo.DisableOutbox()
+	defer cleanupDB(ctx, t)
+
+	order := createSampleOrder("order1", time.Now())
+	require.NoError(t, repo.SaveOrder(ctx, order))
+	require.NoError(t, repo.SaveOrders(ctx, []*models.Order{createSampleOrder("order2", time.Now())}))
+	order.Payment.Amount = 200
+	require.NoError(t, repo.UpsertOrder(ctx, order))
+
+	//заказы сохранены, а событий нет: релей выключен, outbox не должен расти
+	saved, err := repo.GetOrderByUID(ctx, "order2")
+	require.NoError(t, err)
+	assert.Equal(t, "order2", saved.OrderUID)
+	assert.Empty(t, relayAll(ctx, t, repo))
+}
+
+func TestPostgresRepository_RelayOutbox_PublishError(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	require.NoError(t, repo.SaveOrder(ctx, createSampleOrder("order1", time.Now())))
+
+	errPublish := errors.New("broker unavailable")
+	n, err := repo.RelayOutbox(ctx, 100, func(context.Context, []models.OutboxEvent) error {
+		return errPublish
+	})
+	assert.ErrorIs(t, err, errPublish)
+	assert.Zero(t, n)
+
+	//событие не помечено отправленным и уйдёт при следующей попытке
+	assert.Len(t, relayAll(ctx, t, repo), 1)
+}
+
+func TestPostgresRepository_RelayOutbox_SkipLocked(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	require.NoError(t, repo.SaveOrder(ctx, createSampleOrder("order1", time.Now())))
+	require.NoError(t, repo.SaveOrder(ctx, createSampleOrder("order2", time.Now())))
+
+	//пока первый релей держит первое событие, второй забирает только следующее
+	_, err := repo.RelayOutbox(ctx, 1, func(ctx context.Context, events []models.OutboxEvent) error {
+		require.Len(t, events, 1)
+		assert.Equal(t, "order1", events[0].Key)
+
+		other := relayAll(ctx, t, repo)
+		require.Len(t, other, 1)
+		assert.Equal(t, "order2", other[0].Key)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Empty(t, relayAll(ctx, t, repo))
+}
@@ -2,18 +2,66 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"order-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var ErrNotFound = errors.New("order not found")
+var (
+	ErrNotFound       = errors.New("order not found")
+	ErrStatusConflict = errors.New("order status changed concurrently")
+	// ErrOrderExists - заказ с таким же содержимым уже сохранён
+	ErrOrderExists = errors.New("order already exists")
+	// ErrOrderChanged - заказ уже сохранён, но пришёл с другим содержимым
+	ErrOrderChanged = errors.New("order already exists with different content")
+)
+
+const (
+	queryInsertOrder = `INSERT INTO orders
+		(order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status, content_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (order_uid) DO NOTHING`
+
+	// первая запись в истории статусов появляется только один раз, даже если заказ пришёл повторно
+	queryInsertInitialStatus = `INSERT INTO order_status_history
+		(order_id, from_status, to_status, changed_at)
+		SELECT $1, NULL, $2, $3
+		WHERE NOT EXISTS (SELECT 1 FROM order_status_history WHERE order_id = $1)`
+
+	queryInsertPayment = `INSERT INTO payments
+		(order_id, transaction, request_id, currency, provider, amount,payment_dt, bank, delivery_cost, goods_total, custom_fee)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT (order_id) DO NOTHING`
+
+	queryInsertDelivery = `INSERT INTO delivery
+		(order_id, name, phone, zip, city, address, region, email)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		ON CONFLICT (order_id) DO NOTHING`
+
+	queryInsertItem = `INSERT INTO items
+		(order_id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`
+
+	// событие с тем же event_id уже записано - это та же версия заказа
+	queryInsertOutbox = `INSERT INTO outbox
+		(event_id, event_type, aggregate_id, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id) DO NOTHING`
+)
 
 type PostgresRepository struct {
 	db *pgxpool.Pool
+	//события order.saved не пишутся: релей выключен, и их некому отправить и удалить
+	noOutbox bool
 }
 
 func NewPostgresRepository(db *pgxpool.Pool) *PostgresRepository {
@@ -22,15 +70,148 @@ func NewPostgresRepository(db *pgxpool.Pool) *PostgresRepository {
 	}
 }
 
-// SaveOrder - в рамках одной транзакции вставляет в бд всю информацию о заказе
-// В случае конфликта мы ничего не обновляем, потому что в задаче не указано какие поля можно менять, а какие неизменны
+// DisableOutbox - перестаёт писать события order.saved в outbox. Вызывается до начала работы,
+// если релей outbox не запускается: иначе таблица растёт без ограничений
+func (r *PostgresRepository) DisableOutbox() {
+	r.noOutbox = true
+}
+
+// SaveOrder - в рамках одной транзакции вставляет в бд всю информацию о заказе.
+// Вместе с заказом в outbox пишется событие order.saved, если outbox не выключен.
+// Если заказ уже сохранён, ничего не пишется: при совпадении хеша содержимого возвращается ErrOrderExists,
+// иначе ErrOrderChanged, и что делать с новой версией, решает сервисный слой
 func (r *PostgresRepository) SaveOrder(ctx context.Context, order *models.Order) error {
 	const op = "PostgresRepository.SaveOrder"
 
-	queryOrder := `INSERT INTO orders
-		(order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (order_uid) DO NOTHING`
+	hash, err := contentHash(order)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	event, err := r.outboxArgs(order, 1)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, queryInsertOrder, orderArgs(order, hash)...)
+	if err != nil {
+		return fmt.Errorf("%s: insert order %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		changed, err := changedOrders(ctx, tx, map[string]string{order.OrderUID: hash})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(changed) > 0 {
+			return fmt.Errorf("%s: %w", op, ErrOrderChanged)
+		}
+		return fmt.Errorf("%s: %w", op, ErrOrderExists)
+	}
+
+	batch := &pgx.Batch{}
+	queueOrderDetails(batch, order)
+	queueOutbox(batch, event)
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("%s: insert order details %w", op, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SaveOrders - сохраняет пачку новых заказов в одной транзакции.
+// Вставки отправляются через pgx.Batch, поэтому пачка стоит несколько round trip'ов вместо N+3 на каждый заказ.
+// Если хотя бы один заказ уже сохранён, транзакция откатывается с ErrOrderExists или ErrOrderChanged,
+// чтобы такие заказы обработались по одному
+func (r *PostgresRepository) SaveOrders(ctx context.Context, orders []*models.Order) error {
+	const op = "PostgresRepository.SaveOrders"
+
+	if len(orders) == 0 {
+		return nil
+	}
+
+	hashes := make([]string, len(orders))
+	events := make([][]any, len(orders))
+	for i, order := range orders {
+		hash, err := contentHash(order)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		hashes[i] = hash
+		if events[i], err = r.outboxArgs(order, 1); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for i, order := range orders {
+		batch.Queue(queryInsertOrder, orderArgs(order, hashes[i])...)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	existing := make(map[string]string)
+	for i, order := range orders {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return fmt.Errorf("%s: insert order %w", op, err)
+		}
+		if tag.RowsAffected() == 0 {
+			existing[order.OrderUID] = hashes[i]
+		}
+	}
+	if err = results.Close(); err != nil {
+		return fmt.Errorf("%s: insert orders %w", op, err)
+	}
+
+	if len(existing) > 0 {
+		changed, err := changedOrders(ctx, tx, existing)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(changed) > 0 {
+			return fmt.Errorf("%s: %w: %v", op, ErrOrderChanged, changed)
+		}
+		return fmt.Errorf("%s: %w", op, ErrOrderExists)
+	}
+
+	batch = &pgx.Batch{}
+	for i, order := range orders {
+		queueOrderDetails(batch, order)
+		queueOutbox(batch, events[i])
+	}
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("%s: insert order details %w", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: commit %w", op, err)
+	}
+
+	return nil
+}
+
+// UpsertOrder - заменяет сохранённый заказ новой версией: обновляет заказ, оплату и доставку,
+// атомарно пересоздаёт позиции и увеличивает version. Статус заказа не меняется.
+// В outbox пишется событие order.saved с новой версией. Если содержимое не изменилось, ничего не пишется
+func (r *PostgresRepository) UpsertOrder(ctx context.Context, order *models.Order) error {
+	const op = "PostgresRepository.UpsertOrder"
+
+	hash, err := contentHash(order)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -38,7 +219,15 @@ func (r *PostgresRepository) SaveOrder(ctx context.Context, order *models.Order)
 	}
 	defer tx.Rollback(ctx)
 
-	if _, err = tx.Exec(ctx, queryOrder,
+	queryOrder := `UPDATE orders SET
+		track_number = $2, entry = $3, locale = $4, internal_signature = $5, customer_id = $6,
+		delivery_service = $7, shardkey = $8, sm_id = $9, date_created = $10, oof_shard = $11,
+		content_hash = $12, version = version + 1
+		WHERE order_uid = $1 AND content_hash IS DISTINCT FROM $12
+		RETURNING version`
+
+	var version int
+	err = tx.QueryRow(ctx, queryOrder,
 		order.OrderUID,
 		order.TrackNumber,
 		order.Entry,
@@ -50,16 +239,145 @@ func (r *PostgresRepository) SaveOrder(ctx context.Context, order *models.Order)
 		order.SmID,
 		order.DateCreated,
 		order.OofShard,
-	); err != nil {
-		return fmt.Errorf("%s: insert order %w", op, err)
+		hash,
+	).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE order_uid = $1)`, order.OrderUID).Scan(&exists); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !exists {
+			return fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: update order %w", op, err)
 	}
 
-	queryPayments := `INSERT INTO payments
-		(order_id, transaction, request_id, currency, provider, amount,payment_dt, bank, delivery_cost, goods_total, custom_fee)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
-		ON CONFLICT (order_id) DO NOTHING`
+	event, err := r.outboxArgs(order, version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	queryPayment := `UPDATE payments SET
+		transaction = $2, request_id = $3, currency = $4, provider = $5, amount = $6,
+		payment_dt = $7, bank = $8, delivery_cost = $9, goods_total = $10, custom_fee = $11
+		WHERE order_id = $1`
+
+	queryDelivery := `UPDATE delivery SET
+		name = $2, phone = $3, zip = $4, city = $5, address = $6, region = $7, email = $8
+		WHERE order_id = $1`
+
+	batch := &pgx.Batch{}
+	batch.Queue(queryPayment, paymentArgs(order)...)
+	batch.Queue(queryDelivery, deliveryArgs(order)...)
+	batch.Queue(`DELETE FROM items WHERE order_id = $1`, order.OrderUID)
+	for i := range order.Items {
+		batch.Queue(queryInsertItem, itemArgs(order.OrderUID, &order.Items[i])...)
+	}
+	queueOutbox(batch, event)
+
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("%s: update order details %w", op, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// changedOrders - из уже сохранённых заказов выбирает те, чьё содержимое отличается от нового.
+// У заказов, сохранённых до появления хеша, содержимое считается совпадающим
+func changedOrders(ctx context.Context, tx pgx.Tx, hashes map[string]string) ([]string, error) {
+	uids := make([]string, 0, len(hashes))
+	for uid := range hashes {
+		uids = append(uids, uid)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT order_uid, content_hash FROM orders WHERE order_uid = ANY($1)`, uids)
+	if err != nil {
+		return nil, fmt.Errorf("select content hash: %w", err)
+	}
+	defer rows.Close()
+
+	var changed []string
+	for rows.Next() {
+		var uid string
+		var stored *string
+		if err = rows.Scan(&uid, &stored); err != nil {
+			return nil, fmt.Errorf("scan content hash: %w", err)
+		}
+		if stored != nil && *stored != hashes[uid] {
+			changed = append(changed, uid)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows err: %w", err)
+	}
+
+	return changed, nil
+}
 
-	_, err = tx.Exec(ctx, queryPayments,
+// queueOrderDetails - добавляет в пачку вставку истории статусов, оплаты, доставки и позиций заказа
+func queueOrderDetails(batch *pgx.Batch, order *models.Order) {
+	batch.Queue(queryInsertInitialStatus, order.OrderUID, models.StatusCreated, order.DateCreated)
+	batch.Queue(queryInsertPayment, paymentArgs(order)...)
+	batch.Queue(queryInsertDelivery, deliveryArgs(order)...)
+	for i := range order.Items {
+		batch.Queue(queryInsertItem, itemArgs(order.OrderUID, &order.Items[i])...)
+	}
+}
+
+// queueOutbox - добавляет в пачку вставку события outbox; nil - outbox выключен
+func queueOutbox(batch *pgx.Batch, event []any) {
+	if event != nil {
+		batch.Queue(queryInsertOutbox, event...)
+	}
+}
+
+// outboxArgs - событие order.saved для версии заказа, которая пишется в той же транзакции.
+// Если outbox выключен, возвращает nil
+func (r *PostgresRepository) outboxArgs(order *models.Order, version int) ([]any, error) {
+	if r.noOutbox {
+		return nil, nil
+	}
+	event := models.OrderSavedEvent{
+		EventID:    models.OrderSavedEventID(order.OrderUID, version),
+		EventType:  models.EventOrderSaved,
+		OccurredAt: time.Now().UTC(),
+		Version:    version,
+		Order:      order,
+	}
+
+	payload, err := json.Marshal(&event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal outbox event: %w", err)
+	}
+
+	return []any{event.EventID, event.EventType, order.OrderUID, payload}, nil
+}
+
+func orderArgs(order *models.Order, hash string) []any {
+	return []any{
+		order.OrderUID,
+		order.TrackNumber,
+		order.Entry,
+		order.Locale,
+		order.InternalSignature,
+		order.CustomerID,
+		order.DeliveryService,
+		order.Shardkey,
+		order.SmID,
+		order.DateCreated,
+		order.OofShard,
+		//статус из payload не сохраняется: новый заказ всегда created, дальше статус меняют события
+		models.StatusCreated,
+		hash,
+	}
+}
+
+func paymentArgs(order *models.Order) []any {
+	return []any{
 		order.OrderUID,
 		order.Payment.Transaction,
 		order.Payment.RequestID,
@@ -71,17 +389,11 @@ func (r *PostgresRepository) SaveOrder(ctx context.Context, order *models.Order)
 		order.Payment.DeliveryCost,
 		order.Payment.GoodsTotal,
 		order.Payment.CustomFee,
-	)
-	if err != nil {
-		return fmt.Errorf("%s: insert payments %w", op, err)
 	}
+}
 
-	queryDelivery := `INSERT INTO delivery
-		(order_id, name, phone, zip, city, address, region, email)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-		ON CONFLICT (order_id) DO NOTHING`
-
-	_, err = tx.Exec(ctx, queryDelivery,
+func deliveryArgs(order *models.Order) []any {
+	return []any{
 		order.OrderUID,
 		order.Delivery.Name,
 		order.Delivery.Phone,
@@ -90,38 +402,38 @@ func (r *PostgresRepository) SaveOrder(ctx context.Context, order *models.Order)
 		order.Delivery.Address,
 		order.Delivery.Region,
 		order.Delivery.Email,
-	)
-	if err != nil {
-		return fmt.Errorf("%s: insert delivery %w", op, err)
 	}
+}
 
-	queryItem := `INSERT INTO items
-		(order_id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`
-
-	for _, i := range order.Items {
-		_, err = tx.Exec(ctx, queryItem,
-			order.OrderUID,
-			i.ChrtID,
-			i.TrackNumber,
-			i.Price,
-			i.Rid,
-			i.Name,
-			i.Sale,
-			i.Size,
-			i.TotalPrice,
-			i.NmID,
-			i.Brand,
-			i.Status,
-		)
+func itemArgs(orderUID string, i *models.Item) []any {
+	return []any{
+		orderUID,
+		i.ChrtID,
+		i.TrackNumber,
+		i.Price,
+		i.Rid,
+		i.Name,
+		i.Sale,
+		i.Size,
+		i.TotalPrice,
+		i.NmID,
+		i.Brand,
+		i.Status,
+	}
+}
 
-		if err != nil {
-			return fmt.Errorf("%s: insert items %w", op, err)
-		}
+// contentHash - sha256 от JSON заказа без статуса: статус меняется событиями и не входит в содержимое
+func contentHash(order *models.Order) (string, error) {
+	content := *order
+	content.Status = ""
 
+	data, err := json.Marshal(&content)
+	if err != nil {
+		return "", fmt.Errorf("marshal order for hash: %w", err)
 	}
 
-	return tx.Commit(ctx)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
 }
 
 // GetOrderByUID - ищет в бд заказ по UID и возвращает всю структуру заказа
@@ -130,7 +442,7 @@ func (r *PostgresRepository) GetOrderByUID(ctx context.Context, orderUID string)
 
 	query := `SELECT 
     		o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id, 
-    		o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+    		o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
 			d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
 			p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, 
 			p.bank, p.delivery_cost, p.goods_total, p.custom_fee
@@ -143,7 +455,7 @@ func (r *PostgresRepository) GetOrderByUID(ctx context.Context, orderUID string)
 	order.OrderUID = orderUID
 	err := r.db.QueryRow(ctx, query, orderUID).Scan(
 		&order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature, &order.CustomerID,
-		&order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+		&order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard, &order.Status,
 		&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City,
 		&order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
 		&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider,
@@ -187,12 +499,37 @@ func (r *PostgresRepository) GetOrderByUID(ctx context.Context, orderUID string)
 	return &order, nil
 }
 
+// GetOrderByTrackNumber - ищет заказ по трек-номеру. Если трек-номер повторяется, возвращает самый новый заказ
+func (r *PostgresRepository) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	const op = "PostgresRepository.GetOrderByTrackNumber"
+
+	query := `SELECT order_uid FROM orders
+		WHERE track_number = $1
+		ORDER BY date_created DESC, order_uid DESC
+		LIMIT 1`
+
+	var orderUID string
+	if err := r.db.QueryRow(ctx, query, trackNumber).Scan(&orderUID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	order, err := r.GetOrderByUID(ctx, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return order, nil
+}
+
 func (r *PostgresRepository) GetLastNOrders(ctx context.Context, numOrders int) ([]*models.Order, error) {
 	const op = "PostgresRepository.GetLastNOrders"
 
 	query := `SELECT 
 		o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
-        o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+        o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
         d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
         p.transaction, p.request_id, p.currency, p.provider, p.amount, 
         p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
@@ -214,7 +551,7 @@ func (r *PostgresRepository) GetLastNOrders(ctx context.Context, numOrders int)
 		var order models.Order
 		err = rows.Scan(
 			&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
-			&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+			&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard, &order.Status,
 			&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City,
 			&order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
 			&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider,
@@ -280,3 +617,245 @@ func (r *PostgresRepository) GetLastNOrders(ctx context.Context, numOrders int)
 
 	return result, nil
 }
+
+// SearchOrders - ищет заказы по фильтру и возвращает не больше filter.Limit заказов,
+// отсортированных по (date_created, order_uid) от новых к старым, начиная после filter.After
+func (r *PostgresRepository) SearchOrders(ctx context.Context, filter models.OrderFilter) ([]*models.Order, error) {
+	const op = "PostgresRepository.SearchOrders"
+
+	var (
+		conds []string
+		args  []any
+	)
+	//arg - добавляет параметр запроса и возвращает его плейсхолдер
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.CustomerID != "" {
+		conds = append(conds, "o.customer_id = "+arg(filter.CustomerID))
+	}
+	if filter.TrackNumber != "" {
+		conds = append(conds, "o.track_number = "+arg(filter.TrackNumber))
+	}
+	if filter.DeliveryService != "" {
+		conds = append(conds, "o.delivery_service = "+arg(filter.DeliveryService))
+	}
+	if filter.Phone != "" {
+		conds = append(conds, "d.phone = "+arg(filter.Phone))
+	}
+	if filter.Email != "" {
+		conds = append(conds, "d.email = "+arg(filter.Email))
+	}
+	if filter.Transaction != "" {
+		conds = append(conds, "p.transaction = "+arg(filter.Transaction))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conds = append(conds, "o.date_created >= "+arg(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		conds = append(conds, "o.date_created < "+arg(filter.CreatedTo))
+	}
+	//nm_id и brand должны совпасть у одной и той же позиции
+	if filter.NmID != 0 || filter.Brand != "" {
+		itemConds := []string{"i.order_id = o.order_uid"}
+		if filter.NmID != 0 {
+			itemConds = append(itemConds, "i.nm_id = "+arg(filter.NmID))
+		}
+		if filter.Brand != "" {
+			itemConds = append(itemConds, "i.brand = "+arg(filter.Brand))
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM items i WHERE "+strings.Join(itemConds, " AND ")+")")
+	}
+	if filter.After != nil {
+		conds = append(conds, fmt.Sprintf("(o.date_created, o.order_uid) < (%s, %s)",
+			arg(filter.After.DateCreated), arg(filter.After.OrderUID)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	query := `SELECT 
+		o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+        o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
+        d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+        p.transaction, p.request_id, p.currency, p.provider, p.amount, 
+        p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
+		FROM orders o
+		JOIN delivery d ON o.order_uid = d.order_id
+		JOIN payments p ON o.order_uid = p.order_id
+		` + where + `
+		ORDER BY o.date_created DESC, o.order_uid DESC
+		LIMIT ` + arg(filter.Limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	orders := make([]*models.Order, 0, filter.Limit)
+	for rows.Next() {
+		var order models.Order
+		err = rows.Scan(
+			&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+			&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard, &order.Status,
+			&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City,
+			&order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+			&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider,
+			&order.Payment.Amount, &order.Payment.PaymentDT, &order.Payment.Bank, &order.Payment.DeliveryCost,
+			&order.Payment.GoodsTotal, &order.Payment.CustomFee,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan failed: %w", op, err)
+		}
+
+		orders = append(orders, &order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows error: %w", op, err)
+	}
+
+	if err = r.attachItems(ctx, orders); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orders, nil
+}
+
+// attachItems - одним запросом подгружает позиции для списка заказов
+func (r *PostgresRepository) attachItems(ctx context.Context, orders []*models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	orderMap := make(map[string]*models.Order, len(orders))
+	orderUIDs := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderMap[order.OrderUID] = order
+		orderUIDs = append(orderUIDs, order.OrderUID)
+	}
+
+	queryItems := `
+        SELECT 
+            order_id, chrt_id, track_number, price, rid, name, 
+            sale, size, total_price, nm_id, brand, status
+        FROM items
+        WHERE order_id = ANY($1)
+        ORDER BY id`
+
+	rows, err := r.db.Query(ctx, queryItems, orderUIDs)
+	if err != nil {
+		return fmt.Errorf("select items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderUID string
+		var item models.Item
+		err = rows.Scan(
+			&orderUID, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name,
+			&item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status,
+		)
+		if err != nil {
+			return fmt.Errorf("scan item failed: %w", err)
+		}
+
+		if order, exists := orderMap[orderUID]; exists {
+			order.Items = append(order.Items, item)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("items rows error: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrderStatus - возвращает текущий статус заказа
+func (r *PostgresRepository) GetOrderStatus(ctx context.Context, orderUID string) (models.OrderStatus, error) {
+	const op = "PostgresRepository.GetOrderStatus"
+
+	var status models.OrderStatus
+	err := r.db.QueryRow(ctx, `SELECT status FROM orders WHERE order_uid = $1`, orderUID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return status, nil
+}
+
+// UpdateOrderStatus - меняет статус заказа с from на to и пишет запись в историю в одной транзакции.
+// Если статус успел поменяться с момента чтения, возвращает ErrStatusConflict
+func (r *PostgresRepository) UpdateOrderStatus(ctx context.Context, orderUID string, from models.OrderStatus, change models.StatusChange) error {
+	const op = "PostgresRepository.UpdateOrderStatus"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE orders SET status = $1 WHERE order_uid = $2 AND status = $3`,
+		change.To, orderUID, from)
+	if err != nil {
+		return fmt.Errorf("%s: update status %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrStatusConflict)
+	}
+
+	queryHistory := `INSERT INTO order_status_history
+		(order_id, from_status, to_status, changed_at, reason)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))`
+
+	if _, err = tx.Exec(ctx, queryHistory, orderUID, from, change.To, change.ChangedAt, change.Reason); err != nil {
+		return fmt.Errorf("%s: insert status history %w", op, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetStatusHistory - возвращает историю статусов заказа в хронологическом порядке
+func (r *PostgresRepository) GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	const op = "PostgresRepository.GetStatusHistory"
+
+	query := `SELECT COALESCE(from_status, ''), to_status, changed_at, COALESCE(reason, '')
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var history []models.StatusChange
+	for rows.Next() {
+		var change models.StatusChange
+		if err = rows.Scan(&change.From, &change.To, &change.ChangedAt, &change.Reason); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		history = append(history, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows err: %w", op, err)
+	}
+
+	//у каждого сохранённого заказа есть хотя бы запись о создании
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/repository"
+)
+
+const benchBatchSize = 100
+
+func benchOrders(b *testing.B, iter int) []*models.Order {
+	b.Helper()
+	orders := make([]*models.Order, benchBatchSize)
+	for i := range orders {
+		orders[i] = createSampleOrder(fmt.Sprintf("bench-%d-%d", iter, i), time.Now())
+	}
+	return orders
+}
+
+func BenchmarkPostgresRepository_SaveOrder_OneByOne(b *testing.B) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		orders := benchOrders(b, i)
+		b.StartTimer()
+
+		for _, o := range orders {
+			if err := repo.SaveOrder(ctx, o); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.StopTimer()
+	_, _ = testPool.Exec(ctx, "TRUNCATE TABLE outbox, order_status_history, items, payments, delivery, orders RESTART IDENTITY CASCADE")
+}
+
+func BenchmarkPostgresRepository_SaveOrders_Batch(b *testing.B) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		orders := benchOrders(b, i)
+		b.StartTimer()
+
+		if err := repo.SaveOrders(ctx, orders); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+	_, _ = testPool.Exec(ctx, "TRUNCATE TABLE outbox, order_status_history, items, payments, delivery, orders RESTART IDENTITY CASCADE")
+}
@@ -59,7 +59,19 @@ func TestMain(m *testing.M) {
        shardkey VARCHAR,
        sm_id INT,
        date_created TIMESTAMP,
-       oof_shard VARCHAR
+       oof_shard VARCHAR,
+       status VARCHAR NOT NULL DEFAULT 'created',
+       content_hash CHAR(64),
+       version INT NOT NULL DEFAULT 1
+   );
+
+   CREATE TABLE order_status_history (
+       id BIGSERIAL PRIMARY KEY,
+       order_id VARCHAR REFERENCES orders(order_uid),
+       from_status VARCHAR,
+       to_status VARCHAR NOT NULL,
+       changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+       reason VARCHAR
    );
 
 
@@ -104,6 +116,16 @@ func TestMain(m *testing.M) {
        nm_id INT,
        brand VARCHAR,
        status INT
+   );
+
+   CREATE TABLE outbox (
+       id BIGSERIAL PRIMARY KEY,
+       event_id VARCHAR NOT NULL UNIQUE,
+       event_type VARCHAR NOT NULL,
+       aggregate_id VARCHAR NOT NULL,
+       payload JSONB NOT NULL,
+       created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+       sent_at TIMESTAMPTZ
    );`
 
 	_, err = testPool.Exec(ctx, createTables)
@@ -120,7 +142,7 @@ func TestMain(m *testing.M) {
 }
 
 func cleanupDB(ctx context.Context, t *testing.T) {
-	_, err := testPool.Exec(ctx, "TRUNCATE TABLE items, payments, delivery, orders RESTART IDENTITY CASCADE")
+	_, err := testPool.Exec(ctx, "TRUNCATE TABLE outbox, order_status_history, items, payments, delivery, orders RESTART IDENTITY CASCADE")
 	require.NoError(t, err)
 }
 
@@ -137,6 +159,7 @@ func createSampleOrder(uid string, date time.Time) *models.Order {
 		SmID:              1,
 		DateCreated:       date.UTC(),
 		OofShard:          "oof",
+		Status:            models.StatusCreated,
 		Delivery: models.Delivery{
 			Name:    "John Doe",
 			Phone:   "+123456789",
@@ -200,6 +223,12 @@ func TestPostgresRepository_SaveOrder_Update(t *testing.T) {
 	err := repo.SaveOrder(ctx, order)
 	assert.NoError(t, err)
 
+	// Повтор того же заказа ничего не меняет
+	err = repo.SaveOrder(ctx, order)
+	assert.ErrorIs(t, err, repository.ErrOrderExists)
+
+	stored := createSampleOrder("order1", order.DateCreated)
+
 	// Update
 	order.TrackNumber = "updated-track"
 	order.Payment.Amount = 200
@@ -217,13 +246,90 @@ func TestPostgresRepository_SaveOrder_Update(t *testing.T) {
 		Status:      201,
 	})
 
+	// Изменённый заказ не перезаписывает сохранённый
 	err = repo.SaveOrder(ctx, order)
-	assert.NoError(t, err)
+	assert.ErrorIs(t, err, repository.ErrOrderChanged)
 
 	got, err := repo.GetOrderByUID(ctx, "order1")
 	assert.NoError(t, err)
+	assert.Equal(t, stored, got)
+
+	// Upsert заменяет заказ целиком, позиции пересоздаются
+	err = repo.UpsertOrder(ctx, order)
+	assert.NoError(t, err)
+
+	got, err = repo.GetOrderByUID(ctx, "order1")
+	assert.NoError(t, err)
 	assert.Equal(t, order, got)
-	assert.Len(t, got.Items, 2) // Old item replaced? Wait, code deletes old items
+	assert.Len(t, got.Items, 2)
+
+	var version int
+	require.NoError(t, testPool.QueryRow(ctx, "SELECT version FROM orders WHERE order_uid = 'order1'").Scan(&version))
+	assert.Equal(t, 2, version)
+
+	// Повторный upsert того же содержимого версию не меняет
+	require.NoError(t, repo.UpsertOrder(ctx, order))
+	require.NoError(t, testPool.QueryRow(ctx, "SELECT version FROM orders WHERE order_uid = 'order1'").Scan(&version))
+	assert.Equal(t, 2, version)
+}
+
+func TestPostgresRepository_UpsertOrder_NotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+
+	err := repo.UpsertOrder(ctx, createSampleOrder("nonexistent", time.Now()))
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestPostgresRepository_SaveOrders(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	now := time.Now()
+	orders := []*models.Order{
+		createSampleOrder("batch1", now.Add(-time.Hour)),
+		createSampleOrder("batch2", now),
+	}
+
+	err := repo.SaveOrders(ctx, orders)
+	require.NoError(t, err)
+
+	for _, order := range orders {
+		got, err := repo.GetOrderByUID(ctx, order.OrderUID)
+		assert.NoError(t, err)
+		assert.Equal(t, order, got)
+	}
+}
+
+func TestPostgresRepository_SaveOrders_Existing(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	now := time.Now()
+	saved := createSampleOrder("batch1", now)
+	require.NoError(t, repo.SaveOrder(ctx, saved))
+
+	fresh := createSampleOrder("batch2", now)
+	err := repo.SaveOrders(ctx, []*models.Order{fresh, createSampleOrder("batch1", now)})
+	assert.ErrorIs(t, err, repository.ErrOrderExists)
+
+	// пачка откатывается целиком
+	_, err = repo.GetOrderByUID(ctx, "batch2")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	changed := createSampleOrder("batch1", now)
+	changed.Entry = "other"
+	err = repo.SaveOrders(ctx, []*models.Order{fresh, changed})
+	assert.ErrorIs(t, err, repository.ErrOrderChanged)
+}
+
+func TestPostgresRepository_SaveOrders_Empty(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+
+	assert.NoError(t, repo.SaveOrders(ctx, nil))
 }
 
 func TestPostgresRepository_GetOrderByUID_NotFound(t *testing.T) {
@@ -235,6 +341,69 @@ func TestPostgresRepository_GetOrderByUID_NotFound(t *testing.T) {
 	assert.ErrorIs(t, err, repository.ErrNotFound)
 }
 
+func TestPostgresRepository_UpdateOrderStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	order := createSampleOrder("order1", time.Now())
+	require.NoError(t, repo.SaveOrder(ctx, order))
+
+	paidAt := time.Now().UTC().Truncate(time.Microsecond)
+	err := repo.UpdateOrderStatus(ctx, "order1", models.StatusCreated, models.StatusChange{
+		From:      models.StatusCreated,
+		To:        models.StatusPaid,
+		ChangedAt: paidAt,
+		Reason:    "payment received",
+	})
+	require.NoError(t, err)
+
+	status, err := repo.GetOrderStatus(ctx, "order1")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, status)
+
+	history, err := repo.GetStatusHistory(ctx, "order1")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, models.OrderStatus(""), history[0].From)
+	assert.Equal(t, models.StatusCreated, history[0].To)
+	assert.Equal(t, models.StatusPaid, history[1].To)
+	assert.Equal(t, "payment received", history[1].Reason)
+	assert.True(t, paidAt.Equal(history[1].ChangedAt))
+
+	// статус уже не created - обновление с устаревшим from отклоняется
+	err = repo.UpdateOrderStatus(ctx, "order1", models.StatusCreated, models.StatusChange{
+		From: models.StatusCreated,
+		To:   models.StatusCancelled,
+	})
+	assert.ErrorIs(t, err, repository.ErrStatusConflict)
+}
+
+func TestPostgresRepository_SaveOrder_HistoryRecordedOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	order := createSampleOrder("order1", time.Now())
+	require.NoError(t, repo.SaveOrder(ctx, order))
+	require.ErrorIs(t, repo.SaveOrder(ctx, order), repository.ErrOrderExists)
+
+	history, err := repo.GetStatusHistory(ctx, "order1")
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestPostgresRepository_GetStatusHistory_NotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+
+	_, err := repo.GetStatusHistory(ctx, "nonexistent")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	_, err = repo.GetOrderStatus(ctx, "nonexistent")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
 func TestPostgresRepository_GetLastNOrders(t *testing.T) {
 	ctx := context.Background()
 	repo := repository.NewPostgresRepository(testPool)
@@ -268,3 +437,99 @@ func TestPostgresRepository_GetLastNOrders_Empty(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, got)
 }
+
+func TestPostgresRepository_SearchOrders(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	now := time.Now().Truncate(time.Microsecond)
+	order1 := createSampleOrder("order1", now.Add(-3*time.Hour))
+	order2 := createSampleOrder("order2", now.Add(-2*time.Hour))
+	order3 := createSampleOrder("order3", now.Add(-1*time.Hour))
+	order3.CustomerID = "cust2"
+	order3.Delivery.Phone = "+700000000"
+	order3.Items[0].NmID = 777
+	order3.Items[0].Brand = "other"
+
+	for _, o := range []*models.Order{order1, order2, order3} {
+		require.NoError(t, repo.SaveOrder(ctx, o))
+	}
+
+	got, err := repo.SearchOrders(ctx, models.OrderFilter{CustomerID: "cust1", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, order2, got[0])
+	assert.Equal(t, order1, got[1])
+
+	got, err = repo.SearchOrders(ctx, models.OrderFilter{Phone: "+700000000", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "order3", got[0].OrderUID)
+
+	// nm_id и brand должны совпасть у одной позиции
+	got, err = repo.SearchOrders(ctx, models.OrderFilter{NmID: 777, Brand: "brand", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = repo.SearchOrders(ctx, models.OrderFilter{NmID: 777, Brand: "other", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "order3", got[0].OrderUID)
+
+	got, err = repo.SearchOrders(ctx, models.OrderFilter{
+		CreatedFrom: now.Add(-150 * time.Minute),
+		CreatedTo:   now.Add(-time.Hour),
+		Limit:       10,
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "order2", got[0].OrderUID)
+}
+
+func TestPostgresRepository_SearchOrders_Cursor(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	// одинаковое время создания: порядок страниц держится на order_uid
+	now := time.Now().Truncate(time.Microsecond)
+	for _, uid := range []string{"order1", "order2", "order3"} {
+		require.NoError(t, repo.SaveOrder(ctx, createSampleOrder(uid, now)))
+	}
+
+	var uids []string
+	filter := models.OrderFilter{Limit: 2}
+	for {
+		got, err := repo.SearchOrders(ctx, filter)
+		require.NoError(t, err)
+		if len(got) == 0 {
+			break
+		}
+		for _, o := range got {
+			uids = append(uids, o.OrderUID)
+		}
+		last := got[len(got)-1]
+		filter.After = &models.Cursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}
+	}
+
+	assert.Equal(t, []string{"order3", "order2", "order1"}, uids)
+}
+
+func TestPostgresRepository_GetOrderByTrackNumber(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(testPool)
+	defer cleanupDB(ctx, t)
+
+	now := time.Now()
+	order := createSampleOrder("order1", now)
+	require.NoError(t, repo.SaveOrder(ctx, order))
+
+	got, err := repo.GetOrderByTrackNumber(ctx, "track-order1")
+	require.NoError(t, err)
+	assert.Equal(t, "order1", got.OrderUID)
+	assert.Len(t, got.Items, 1)
+
+	_, err = repo.GetOrderByTrackNumber(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
@@ -1,20 +1,47 @@
 package router
 
 import (
+	"net/http"
 	"order-service/internal/handlers"
+	"order-service/internal/health"
+	"order-service/internal/metrics"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func InitRouter(orderHandler *handlers.Handler) *gin.Engine {
+func InitRouter(orderHandler *handlers.Handler, ingestHandler *handlers.IngestHandler, feedHandler *handlers.FeedHandler, checker *health.Checker) *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Recovery())
+	router.Use(metrics.GinMiddleware())
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/healthz", checker.Liveness)
+	router.GET("/readyz", checker.Readiness)
 
 	order := router.Group("/order")
 	{
+		order.POST("", orderHandler.CreateOrder)
 		order.GET("/:order_uid", orderHandler.GetOrderByUID)
+		order.GET("/:order_uid/history", orderHandler.GetOrderHistory)
 	}
 
+	router.GET("/orders", orderHandler.SearchOrders)
+	router.GET("/orders/stream", feedHandler.StreamSSE)
+	router.GET("/orders/stream/ws", feedHandler.StreamWS)
+	//в gin нельзя экранировать ':' в пути, поэтому /orders:batch регистрируется как параметр
+	//и разбирается вручную, остальные /orders:* - 404
+	router.POST("/orders:method", func(c *gin.Context) {
+		if c.Param("method") != ":batch" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		orderHandler.CreateOrders(c)
+	})
+	router.POST("/ingest/orders", ingestHandler.IngestOrder)
+	router.GET("/track/:track_number", orderHandler.GetOrderByTrack)
+	router.GET("/customers/:customer_id/orders", orderHandler.GetCustomerOrders)
+
 	router.Static("/static", "./web/static")
 	router.StaticFile("/", "./web/static/index.html")
 
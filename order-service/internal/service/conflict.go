@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"order-service/internal/models"
+)
+
+// ConflictPolicy - что делать, если заказ с уже сохранённым order_uid пришёл с другим содержимым.
+// Повтор с тем же содержимым всегда ничего не меняет
+type ConflictPolicy string
+
+const (
+	// ConflictIgnore - оставить сохранённую версию, изменённый заказ только логируется
+	ConflictIgnore ConflictPolicy = "ignore"
+	// ConflictReject - отклонить изменённый заказ, консьюмер отправит его в DLQ
+	ConflictReject ConflictPolicy = "reject"
+	// ConflictUpsert - заменить сохранённую версию новой с увеличением version
+	ConflictUpsert ConflictPolicy = "upsert"
+)
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictIgnore, ConflictReject, ConflictUpsert:
+		return p, nil
+	case "":
+		return ConflictIgnore, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q", s)
+}
+
+// handleChangedOrder - применяет политику конфликтов к заказу, который уже сохранён с другим содержимым
+func (s *OrderService) handleChangedOrder(ctx context.Context, order *models.Order, cause error, log *slog.Logger) error {
+	const op = "OrderService.handleChangedOrder"
+
+	switch s.conflictPolicy {
+	case ConflictReject:
+		log.Warn("order redelivered with different content, rejecting")
+		return fmt.Errorf("%s: %w", op, cause)
+
+	case ConflictUpsert:
+		if err := s.db.UpsertOrder(ctx, order); err != nil {
+			log.Error("failed to upsert order", slog.Any("error", err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		//статус меняется только событиями, поэтому в кеш кладём заказ с текущим статусом из бд
+		status, err := s.db.GetOrderStatus(ctx, order.OrderUID)
+		if err != nil {
+			log.Error("failed to get order status after upsert", slog.Any("error", err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		order.Status = status
+
+		s.cache.Set(order)
+		s.publish(order)
+		log.Info("order replaced with new version")
+		return nil
+
+	default:
+		log.Warn("order redelivered with different content, keeping stored version")
+		return nil
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/service/mocks"
+)
+
+func TestParseConflictPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"ignore", "reject", "upsert"} {
+		p, err := ParseConflictPolicy(s)
+		require.NoError(t, err)
+		assert.Equal(t, ConflictPolicy(s), p)
+	}
+
+	p, err := ParseConflictPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, ConflictIgnore, p)
+
+	_, err = ParseConflictPolicy("overwrite")
+	assert.Error(t, err)
+}
+
+func TestOrderService_ProcessNewOrder_IdenticalReplay(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []ConflictPolicy{ConflictIgnore, ConflictReject, ConflictUpsert} {
+		repo := new(mocks.OrderRepository)
+		cache := new(mocks.OrderCache)
+
+		svc := NewOrderService(repo, cache, testLogger(), policy)
+		order := &models.Order{OrderUID: "uid-1"}
+
+		repo.On("SaveOrder", mock.Anything, order).Return(repository.ErrOrderExists).Once()
+
+		err := svc.ProcessNewOrder(context.Background(), order)
+		require.NoError(t, err, policy)
+
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "UpsertOrder", mock.Anything, mock.Anything)
+		cache.AssertNotCalled(t, "Set", mock.Anything)
+	}
+}
+
+func TestOrderService_ProcessNewOrder_ChangedIgnore(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	order := &models.Order{OrderUID: "uid-1"}
+
+	repo.On("SaveOrder", mock.Anything, order).Return(repository.ErrOrderChanged).Once()
+
+	err := svc.ProcessNewOrder(context.Background(), order)
+	require.NoError(t, err)
+
+	repo.AssertNotCalled(t, "UpsertOrder", mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "Set", mock.Anything)
+}
+
+func TestOrderService_ProcessNewOrder_ChangedReject(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictReject)
+	order := &models.Order{OrderUID: "uid-1"}
+
+	repo.On("SaveOrder", mock.Anything, order).Return(repository.ErrOrderChanged).Once()
+
+	err := svc.ProcessNewOrder(context.Background(), order)
+	assert.ErrorIs(t, err, repository.ErrOrderChanged)
+
+	cache.AssertNotCalled(t, "Set", mock.Anything)
+}
+
+func TestOrderService_ProcessNewOrder_ChangedUpsert(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictUpsert)
+	order := &models.Order{OrderUID: "uid-1"}
+
+	repo.On("SaveOrder", mock.Anything, order).Return(repository.ErrOrderChanged).Once()
+	repo.On("UpsertOrder", mock.Anything, order).Return(nil).Once()
+	repo.On("GetOrderStatus", mock.Anything, "uid-1").Return(models.StatusShipped, nil).Once()
+	cache.On("Set", order).Once()
+
+	err := svc.ProcessNewOrder(context.Background(), order)
+	require.NoError(t, err)
+
+	// статус в кеше - текущий из бд, а не из нового сообщения
+	assert.Equal(t, models.StatusShipped, order.Status)
+}
+
+func TestOrderService_CreateOrder_New(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	order := &models.Order{OrderUID: "uid-1"}
+
+	repo.On("SaveOrder", mock.Anything, order).Return(nil).Once()
+	cache.On("Set", order).Once()
+
+	require.NoError(t, svc.CreateOrder(context.Background(), order))
+	assert.Equal(t, models.StatusCreated, order.Status)
+}
+
+func TestOrderService_CreateOrder_Conflicts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		policy  ConflictPolicy
+		saveErr error
+	}{
+		{policy: ConflictIgnore, saveErr: repository.ErrOrderExists},
+		{policy: ConflictIgnore, saveErr: repository.ErrOrderChanged},
+		{policy: ConflictReject, saveErr: repository.ErrOrderChanged},
+		{policy: ConflictUpsert, saveErr: repository.ErrOrderExists},
+	}
+
+	for _, tt := range tests {
+		repo := new(mocks.OrderRepository)
+		cache := new(mocks.OrderCache)
+
+		svc := NewOrderService(repo, cache, testLogger(), tt.policy)
+		order := &models.Order{OrderUID: "uid-1"}
+
+		repo.On("SaveOrder", mock.Anything, order).Return(tt.saveErr).Once()
+
+		//в отличие от ProcessNewOrder повтор возвращается вызывающему
+		err := svc.CreateOrder(context.Background(), order)
+		assert.ErrorIs(t, err, tt.saveErr, tt.policy)
+
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "UpsertOrder", mock.Anything, mock.Anything)
+		cache.AssertNotCalled(t, "Set", mock.Anything)
+	}
+}
+
+func TestOrderService_CreateOrder_ChangedUpsert(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictUpsert)
+	order := &models.Order{OrderUID: "uid-1"}
+
+	repo.On("SaveOrder", mock.Anything, order).Return(repository.ErrOrderChanged).Once()
+	repo.On("UpsertOrder", mock.Anything, order).Return(nil).Once()
+	repo.On("GetOrderStatus", mock.Anything, "uid-1").Return(models.StatusPaid, nil).Once()
+	cache.On("Set", order).Once()
+
+	require.NoError(t, svc.CreateOrder(context.Background(), order))
+	assert.Equal(t, models.StatusPaid, order.Status)
+}
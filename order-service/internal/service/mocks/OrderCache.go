@@ -43,6 +43,36 @@ func (_m *OrderCache) Get(_a0 string) (*models.Order, bool) {
 	return r0, r1
 }
 
+// GetByTrack provides a mock function with given fields: _a0
+func (_m *OrderCache) GetByTrack(_a0 string) (*models.Order, bool) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByTrack")
+	}
+
+	var r0 *models.Order
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string) (*models.Order, bool)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.Order); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
 // LoadBatch provides a mock function with given fields: _a0
 func (_m *OrderCache) LoadBatch(_a0 []*models.Order) {
 	_m.Called(_a0)
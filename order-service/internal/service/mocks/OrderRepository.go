@@ -44,6 +44,36 @@ func (_m *OrderRepository) GetLastNOrders(_a0 context.Context, _a1 int) ([]*mode
 	return r0, r1
 }
 
+// GetOrderByTrackNumber provides a mock function with given fields: _a0, _a1
+func (_m *OrderRepository) GetOrderByTrackNumber(_a0 context.Context, _a1 string) (*models.Order, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderByTrackNumber")
+	}
+
+	var r0 *models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Order, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Order); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOrderByUID provides a mock function with given fields: _a0, _a1
 func (_m *OrderRepository) GetOrderByUID(_a0 context.Context, _a1 string) (*models.Order, error) {
 	ret := _m.Called(_a0, _a1)
@@ -74,6 +104,64 @@ func (_m *OrderRepository) GetOrderByUID(_a0 context.Context, _a1 string) (*mode
 	return r0, r1
 }
 
+// GetOrderStatus provides a mock function with given fields: _a0, _a1
+func (_m *OrderRepository) GetOrderStatus(_a0 context.Context, _a1 string) (models.OrderStatus, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderStatus")
+	}
+
+	var r0 models.OrderStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.OrderStatus, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.OrderStatus); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(models.OrderStatus)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStatusHistory provides a mock function with given fields: _a0, _a1
+func (_m *OrderRepository) GetStatusHistory(_a0 context.Context, _a1 string) ([]models.StatusChange, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStatusHistory")
+	}
+
+	var r0 []models.StatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.StatusChange, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.StatusChange); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveOrder provides a mock function with given fields: _a0, _a1
 func (_m *OrderRepository) SaveOrder(_a0 context.Context, _a1 *models.Order) error {
 	ret := _m.Called(_a0, _a1)
@@ -92,6 +180,90 @@ func (_m *OrderRepository) SaveOrder(_a0 context.Context, _a1 *models.Order) err
 	return r0
 }
 
+// SaveOrders provides a mock function with given fields: _a0, _a1
+func (_m *OrderRepository) SaveOrders(_a0 context.Context, _a1 []*models.Order) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveOrders")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.Order) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SearchOrders provides a mock function with given fields: _a0, _a1
+func (_m *OrderRepository) SearchOrders(_a0 context.Context, _a1 models.OrderFilter) ([]*models.Order, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchOrders")
+	}
+
+	var r0 []*models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) ([]*models.Order, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) []*models.Order); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.OrderFilter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateOrderStatus provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *OrderRepository) UpdateOrderStatus(_a0 context.Context, _a1 string, _a2 models.OrderStatus, _a3 models.StatusChange) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateOrderStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.OrderStatus, models.StatusChange) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertOrder provides a mock function with given fields: _a0, _a1
+func (_m *OrderRepository) UpsertOrder(_a0 context.Context, _a1 *models.Order) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Order) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewOrderRepository creates a new instance of OrderRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewOrderRepository(t interface {
@@ -7,31 +7,51 @@ import (
 	"log/slog"
 	"order-service/internal/models"
 	"order-service/internal/repository"
+
+	"golang.org/x/sync/singleflight"
 )
 
 type OrderRepository interface {
 	SaveOrder(context.Context, *models.Order) error
+	SaveOrders(context.Context, []*models.Order) error
+	UpsertOrder(context.Context, *models.Order) error
 	GetOrderByUID(context.Context, string) (*models.Order, error)
+	GetOrderByTrackNumber(context.Context, string) (*models.Order, error)
 	GetLastNOrders(context.Context, int) ([]*models.Order, error)
+	GetOrderStatus(context.Context, string) (models.OrderStatus, error)
+	UpdateOrderStatus(context.Context, string, models.OrderStatus, models.StatusChange) error
+	GetStatusHistory(context.Context, string) ([]models.StatusChange, error)
+	SearchOrders(context.Context, models.OrderFilter) ([]*models.Order, error)
 }
 
 type OrderCache interface {
 	Set(*models.Order)
 	Get(string) (*models.Order, bool)
+	GetByTrack(string) (*models.Order, bool)
 	LoadBatch([]*models.Order)
 }
 
+// OrderFeed - получает каждый сохранённый заказ, например для живой ленты. Publish не должен блокироваться
+type OrderFeed interface {
+	Publish(*models.Order)
+}
+
 type OrderService struct {
-	db    OrderRepository
-	cache OrderCache
-	log   *slog.Logger
+	db             OrderRepository
+	cache          OrderCache
+	log            *slog.Logger
+	conflictPolicy ConflictPolicy
+	feed           OrderFeed
+	//одновременные промахи кеша по одному order_uid ждут один запрос к бд
+	loads singleflight.Group
 }
 
-func NewOrderService(db OrderRepository, cache OrderCache, log *slog.Logger) *OrderService {
+func NewOrderService(db OrderRepository, cache OrderCache, log *slog.Logger, conflictPolicy ConflictPolicy) *OrderService {
 	return &OrderService{
-		db:    db,
-		cache: cache,
-		log:   log,
+		db:             db,
+		cache:          cache,
+		log:            log,
+		conflictPolicy: conflictPolicy,
 	}
 }
 
@@ -44,17 +64,102 @@ func (s *OrderService) ProcessNewOrder(ctx context.Context, order *models.Order)
 
 	log.Info("starting to process new order")
 
-	if err := s.db.SaveOrder(ctx, order); err != nil {
+	if err := s.saveOrder(ctx, order); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrOrderExists):
+			//повтор того же сообщения - в бд и кеше уже актуальная версия
+			log.Info("order already saved, skipping")
+			return nil
+		case errors.Is(err, repository.ErrOrderChanged):
+			return s.handleChangedOrder(ctx, order, err, log)
+		}
 		log.Error("failed to save order to repository", slog.Any("error", err))
-		return fmt.Errorf("%s: %v", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	s.cache.Set(order)
 	log.Info("order processed and cached successfully")
 
 	return nil
 }
 
+// CreateOrder - сохраняет заказ, принятый по HTTP. В отличие от ProcessNewOrder повтор не скрывается:
+// если заказ уже сохранён, возвращается repository.ErrOrderExists, если сохранён с другим содержимым -
+// repository.ErrOrderChanged. При политике upsert изменённый заказ перезаписывается
+func (s *OrderService) CreateOrder(ctx context.Context, order *models.Order) error {
+	const op = "OrderService.CreateOrder"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", order.OrderUID),
+	)
+
+	err := s.saveOrder(ctx, order)
+	switch {
+	case err == nil:
+		log.Info("order created")
+		return nil
+	case errors.Is(err, repository.ErrOrderChanged) && s.conflictPolicy == ConflictUpsert:
+		return s.handleChangedOrder(ctx, order, err, log)
+	case errors.Is(err, repository.ErrOrderExists), errors.Is(err, repository.ErrOrderChanged):
+		log.Info("order already exists", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Error("failed to save order to repository", slog.Any("error", err))
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// SetFeed - подключает получателя сохранённых заказов. Вызывается до запуска консьюмеров и HTTP-сервера
+func (s *OrderService) SetFeed(feed OrderFeed) {
+	s.feed = feed
+}
+
+// saveOrder - сохраняет новый заказ и кладёт его в кеш. Ошибки репозитория возвращаются как есть
+func (s *OrderService) saveOrder(ctx context.Context, order *models.Order) error {
+	//статус меняется только событиями по таблице переходов, поэтому новый заказ всегда created
+	order.Status = models.StatusCreated
+
+	if err := s.db.SaveOrder(ctx, order); err != nil {
+		return err
+	}
+
+	s.cache.Set(order)
+	s.publish(order)
+	return nil
+}
+
+func (s *OrderService) publish(order *models.Order) {
+	if s.feed != nil {
+		s.feed.Publish(order)
+	}
+}
+
+// ProcessNewOrders - сохраняет пачку заказов одной транзакцией и кладёт их в кеш
+func (s *OrderService) ProcessNewOrders(ctx context.Context, orders []*models.Order) error {
+	const op = "OrderService.ProcessNewOrders"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("orders", len(orders)),
+	)
+
+	log.Info("starting to process batch of orders")
+
+	for _, order := range orders {
+		order.Status = models.StatusCreated
+	}
+
+	if err := s.db.SaveOrders(ctx, orders); err != nil {
+		log.Error("failed to save orders to repository", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, order := range orders {
+		s.cache.Set(order)
+		s.publish(order)
+	}
+	log.Info("batch processed and cached successfully")
+
+	return nil
+}
+
 func (s *OrderService) GetOrderByUID(ctx context.Context, orderUID string) (*models.Order, error) {
 	const op = "OrderService.GetOrderByUID"
 
@@ -62,6 +167,47 @@ func (s *OrderService) GetOrderByUID(ctx context.Context, orderUID string) (*mod
 		return order, nil
 	}
 
+	order, err := s.loadOrder(ctx, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return order, nil
+}
+
+// loadOrder - читает заказ из бд и кладёт в кеш. Если этот order_uid уже читается, ждёт тот же запрос:
+// после сброса кеша популярный заказ не превращается в сотни одинаковых запросов к Postgres
+func (s *OrderService) loadOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	for {
+		//led выставляется, только если запрос к бд запустил этот вызов, а не другой клиент
+		led := false
+		ch := s.loads.DoChan(orderUID, func() (any, error) {
+			led = true
+			return s.fetchOrder(ctx, orderUID)
+		})
+
+		var res singleflight.Result
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res = <-ch:
+		}
+
+		if !led && isContextErr(res.Err) && ctx.Err() == nil {
+			//запрос выполнялся с контекстом другого клиента, и тот ушёл. Ожидавшие встают в новый общий запрос,
+			//а не идут в бд каждый сам
+			continue
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*models.Order), nil
+	}
+}
+
+func (s *OrderService) fetchOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	const op = "OrderService.GetOrderByUID"
+
 	order, err := s.db.GetOrderByUID(ctx, orderUID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
@@ -76,7 +222,7 @@ func (s *OrderService) GetOrderByUID(ctx context.Context, orderUID string) (*mod
 				slog.Any("error", err),
 			)
 		}
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
 
 	if order != nil {
@@ -86,6 +232,40 @@ func (s *OrderService) GetOrderByUID(ctx context.Context, orderUID string) (*mod
 	return order, nil
 }
 
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// GetOrderByTrackNumber - ищет заказ по трек-номеру сначала в кеше, затем в бд
+func (s *OrderService) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	const op = "OrderService.GetOrderByTrackNumber"
+
+	if order, ok := s.cache.GetByTrack(trackNumber); ok {
+		return order, nil
+	}
+
+	order, err := s.db.GetOrderByTrackNumber(ctx, trackNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.log.Warn("order not found in repository",
+				slog.String("op", op),
+				slog.String("track_number", trackNumber),
+			)
+		} else {
+			s.log.Error("failed to get order from repository",
+				slog.String("op", op),
+				slog.String("track_number", trackNumber),
+				slog.Any("error", err),
+			)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.cache.Set(order)
+
+	return order, nil
+}
+
 func (s *OrderService) PreloadCache(ctx context.Context, numOrders int) error {
 	const op = "OrderService.PreloadCache"
 	log := s.log.With(slog.String("op", op))
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -28,7 +30,7 @@ func TestOrderService_ProcessNewOrder_Success(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 	order := &models.Order{OrderUID: "uid-1"}
 
@@ -39,6 +41,34 @@ func TestOrderService_ProcessNewOrder_Success(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestOrderService_ProcessNewOrder_IgnoresPayloadStatus(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	ctx := context.Background()
+
+	//статус из сообщения не должен обходить таблицу переходов
+	order := &models.Order{OrderUID: "uid-delivered", Status: models.StatusDelivered}
+	created := mock.MatchedBy(func(o *models.Order) bool { return o.Status == models.StatusCreated })
+	repo.On("SaveOrder", mock.Anything, created).Return(nil).Once()
+	cache.On("Set", created).Once()
+	require.NoError(t, svc.ProcessNewOrder(ctx, order))
+
+	batch := []*models.Order{{OrderUID: "b1", Status: models.StatusPaid}, {OrderUID: "b2", Status: models.StatusShipped}}
+	repo.On("SaveOrders", mock.Anything, batch).Return(nil).Once()
+	cache.On("Set", created).Twice()
+	require.NoError(t, svc.ProcessNewOrders(ctx, batch))
+
+	for _, o := range batch {
+		assert.Equal(t, models.StatusCreated, o.Status)
+	}
+}
+
 func TestOrderService_ProcessNewOrder_SaveError(t *testing.T) {
 	t.Parallel()
 
@@ -47,7 +77,7 @@ func TestOrderService_ProcessNewOrder_SaveError(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 	order := &models.Order{OrderUID: "uid-err"}
 
@@ -59,6 +89,49 @@ func TestOrderService_ProcessNewOrder_SaveError(t *testing.T) {
 	cache.AssertNotCalled(t, "Set", mock.Anything)
 }
 
+func TestOrderService_ProcessNewOrders_Success(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	ctx := context.Background()
+	orders := []*models.Order{
+		{OrderUID: "b1"},
+		{OrderUID: "b2"},
+	}
+
+	repo.On("SaveOrders", mock.Anything, orders).Return(nil).Once()
+	cache.On("Set", orders[0]).Once()
+	cache.On("Set", orders[1]).Once()
+
+	err := svc.ProcessNewOrders(ctx, orders)
+	require.NoError(t, err)
+}
+
+func TestOrderService_ProcessNewOrders_SaveError(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	ctx := context.Background()
+	orders := []*models.Order{{OrderUID: "b-err"}}
+
+	repo.On("SaveOrders", mock.Anything, orders).Return(errors.New("db failed")).Once()
+
+	err := svc.ProcessNewOrders(ctx, orders)
+	require.Error(t, err)
+
+	cache.AssertNotCalled(t, "Set", mock.Anything)
+}
+
 func TestOrderService_GetOrderByUID_CacheHit(t *testing.T) {
 	t.Parallel()
 
@@ -67,7 +140,7 @@ func TestOrderService_GetOrderByUID_CacheHit(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 	order := &models.Order{OrderUID: "uid-2"}
 
@@ -89,7 +162,7 @@ func TestOrderService_GetOrderByUID_CacheMiss_FoundInRepo(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 	order := &models.Order{OrderUID: "uid-3"}
 
@@ -111,7 +184,7 @@ func TestOrderService_GetOrderByUID_CacheMiss_NotFound(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 
 	cache.On("Get", "uid-404").Return((*models.Order)(nil), false).Once()
@@ -134,7 +207,7 @@ func TestOrderService_GetOrderByUID_CacheMiss_RepoError(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 	someErr := errors.New("db explosion")
 
@@ -149,6 +222,65 @@ func TestOrderService_GetOrderByUID_CacheMiss_RepoError(t *testing.T) {
 	cache.AssertNotCalled(t, "Set", mock.Anything)
 }
 
+func TestOrderService_GetOrderByTrackNumber_CacheHit(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	order := &models.Order{OrderUID: "uid-1", TrackNumber: "TRK-1"}
+
+	cache.On("GetByTrack", "TRK-1").Return(order, true).Once()
+
+	got, err := svc.GetOrderByTrackNumber(context.Background(), "TRK-1")
+	require.NoError(t, err)
+	assert.Equal(t, order, got)
+
+	repo.AssertNotCalled(t, "GetOrderByTrackNumber", mock.Anything, mock.Anything)
+}
+
+func TestOrderService_GetOrderByTrackNumber_CacheMiss_FoundInRepo(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	order := &models.Order{OrderUID: "uid-1", TrackNumber: "TRK-1"}
+
+	cache.On("GetByTrack", "TRK-1").Return((*models.Order)(nil), false).Once()
+	repo.On("GetOrderByTrackNumber", mock.Anything, "TRK-1").Return(order, nil).Once()
+	cache.On("Set", order).Once()
+
+	got, err := svc.GetOrderByTrackNumber(context.Background(), "TRK-1")
+	require.NoError(t, err)
+	assert.Equal(t, order, got)
+}
+
+func TestOrderService_GetOrderByTrackNumber_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+
+	cache.On("GetByTrack", "TRK-404").Return((*models.Order)(nil), false).Once()
+	repo.On("GetOrderByTrackNumber", mock.Anything, "TRK-404").Return(nil, repository.ErrNotFound).Once()
+
+	got, err := svc.GetOrderByTrackNumber(context.Background(), "TRK-404")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.Nil(t, got)
+
+	cache.AssertNotCalled(t, "Set", mock.Anything)
+}
+
 func TestOrderService_PreloadCache_Success(t *testing.T) {
 	t.Parallel()
 
@@ -157,7 +289,7 @@ func TestOrderService_PreloadCache_Success(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 	orders := []*models.Order{
 		{OrderUID: "o1"},
@@ -180,7 +312,7 @@ func TestOrderService_PreloadCache_RepoError(t *testing.T) {
 	defer repo.AssertExpectations(t)
 	defer cache.AssertExpectations(t)
 
-	svc := NewOrderService(repo, cache, testLogger())
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
 	ctx := context.Background()
 
 	repo.On("GetLastNOrders", mock.Anything, 5).Return(nil, errors.New("db fail")).Once()
@@ -190,3 +322,170 @@ func TestOrderService_PreloadCache_RepoError(t *testing.T) {
 
 	cache.AssertNotCalled(t, "LoadBatch", mock.Anything)
 }
+
+type recordingFeed struct {
+	orders []string
+}
+
+func (f *recordingFeed) Publish(o *models.Order) {
+	f.orders = append(f.orders, o.OrderUID)
+}
+
+func TestOrderService_Feed(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	feed := &recordingFeed{}
+	svc.SetFeed(feed)
+	ctx := context.Background()
+
+	repo.On("SaveOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool { return o.OrderUID == "uid-new" })).
+		Return(nil).Once()
+	repo.On("SaveOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool { return o.OrderUID == "uid-dup" })).
+		Return(repository.ErrOrderExists).Once()
+	repo.On("SaveOrder", mock.Anything, mock.MatchedBy(func(o *models.Order) bool { return o.OrderUID == "uid-err" })).
+		Return(errors.New("db failed")).Once()
+	repo.On("SaveOrders", mock.Anything, mock.Anything).Return(nil).Once()
+	cache.On("Set", mock.Anything)
+
+	require.NoError(t, svc.ProcessNewOrder(ctx, &models.Order{OrderUID: "uid-new"}))
+	require.NoError(t, svc.ProcessNewOrder(ctx, &models.Order{OrderUID: "uid-dup"}))
+	require.Error(t, svc.ProcessNewOrder(ctx, &models.Order{OrderUID: "uid-err"}))
+	require.NoError(t, svc.ProcessNewOrders(ctx, []*models.Order{{OrderUID: "uid-b1"}, {OrderUID: "uid-b2"}}))
+
+	//в ленту попадают только сохранённые заказы, повторы и ошибки - нет
+	assert.Equal(t, []string{"uid-new", "uid-b1", "uid-b2"}, feed.orders)
+}
+
+// missStorm - n одновременных GetOrderByUID по одному uid. Репозиторий отвечает, только когда
+// все n запросов прошли мимо кеша, поэтому каждый из них застаёт чтение из бд в процессе
+func missStorm(t *testing.T, n int, repoOrder *models.Order, repoErr error) ([]*models.Order, []error, *mocks.OrderRepository, *mocks.OrderCache) {
+	t.Helper()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+
+	var missed sync.WaitGroup
+	missed.Add(n)
+	cache.On("Get", "uid-hot").Return((*models.Order)(nil), false).Times(n).
+		Run(func(mock.Arguments) { missed.Done() })
+	repo.On("GetOrderByUID", mock.Anything, "uid-hot").Return(repoOrder, repoErr).Once().
+		Run(func(mock.Arguments) {
+			missed.Wait()
+			//запрос от кеша до ожидания в singleflight
+			time.Sleep(50 * time.Millisecond)
+		})
+	if repoOrder != nil {
+		cache.On("Set", repoOrder).Once()
+	}
+
+	orders := make([]*models.Order, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			orders[i], errs[i] = svc.GetOrderByUID(context.Background(), "uid-hot")
+		}()
+	}
+	wg.Wait()
+
+	return orders, errs, repo, cache
+}
+
+func TestOrderService_GetOrderByUID_CoalescesMisses(t *testing.T) {
+	t.Parallel()
+
+	order := &models.Order{OrderUID: "uid-hot"}
+	orders, errs, repo, cache := missStorm(t, 50, order, nil)
+
+	for i := range orders {
+		require.NoError(t, errs[i])
+		assert.Same(t, order, orders[i])
+	}
+	repo.AssertNumberOfCalls(t, "GetOrderByUID", 1)
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func TestOrderService_GetOrderByUID_CoalescesMisses_Error(t *testing.T) {
+	t.Parallel()
+
+	_, errs, repo, cache := missStorm(t, 20, nil, repository.ErrNotFound)
+
+	for _, err := range errs {
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	}
+	repo.AssertNumberOfCalls(t, "GetOrderByUID", 1)
+	cache.AssertNotCalled(t, "Set", mock.Anything)
+}
+
+func TestOrderService_GetOrderByUID_LeaderCanceled(t *testing.T) {
+	t.Parallel()
+
+	const followers = 10
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	order := &models.Order{OrderUID: "uid-hot"}
+
+	started := make(chan struct{})
+	var missed sync.WaitGroup
+	missed.Add(followers)
+	cache.On("Get", "uid-hot").Return((*models.Order)(nil), false).Once()
+	cache.On("Get", "uid-hot").Return((*models.Order)(nil), false).Times(followers).
+		Run(func(mock.Arguments) { missed.Done() })
+	//первый запрос к бд идёт с контекстом первого клиента и прерывается вместе с ним
+	repo.On("GetOrderByUID", mock.Anything, "uid-hot").Return(nil, context.Canceled).Once().
+		Run(func(args mock.Arguments) {
+			close(started)
+			<-args.Get(0).(context.Context).Done()
+		})
+	//повторный запрос - один на всех ожидавших, отвечает не сразу, чтобы все успели в него встать
+	repo.On("GetOrderByUID", mock.Anything, "uid-hot").Return(order, nil).Once().
+		Run(func(mock.Arguments) { time.Sleep(50 * time.Millisecond) })
+	cache.On("Set", order).Once()
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := svc.GetOrderByUID(leaderCtx, "uid-hot")
+		leaderErr <- err
+	}()
+	<-started
+
+	orders := make([]*models.Order, followers)
+	errs := make([]error, followers)
+	var wg sync.WaitGroup
+	for i := 0; i < followers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			orders[i], errs[i] = svc.GetOrderByUID(context.Background(), "uid-hot")
+		}()
+	}
+	missed.Wait()
+	//запрос от кеша до ожидания в singleflight
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	//уход первого клиента не обрывает запросы остальных, и они не идут в бд каждый сам
+	wg.Wait()
+	for i := range orders {
+		require.NoError(t, errs[i])
+		assert.Same(t, order, orders[i])
+	}
+	repo.AssertNumberOfCalls(t, "GetOrderByUID", 2)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"order-service/internal/models"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchOrders - возвращает страницу заказов по фильтру. Результаты поиска не кешируются:
+// кеш хранит заказы только по order_uid
+func (s *OrderService) SearchOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error) {
+	const op = "OrderService.SearchOrders"
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultSearchLimit
+	case filter.Limit > MaxSearchLimit:
+		filter.Limit = MaxSearchLimit
+	}
+	limit := filter.Limit
+
+	//запрашиваем на один заказ больше, чтобы понять, есть ли следующая страница
+	filter.Limit++
+	orders, err := s.db.SearchOrders(ctx, filter)
+	if err != nil {
+		s.log.Error("failed to search orders in repository",
+			slog.String("op", op),
+			slog.Any("error", err),
+		)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page := &models.OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		last := page.Orders[limit-1]
+		page.NextCursor = models.Cursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}.Encode()
+	}
+
+	return page, nil
+}
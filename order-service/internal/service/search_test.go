@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/models"
+	"order-service/internal/service/mocks"
+)
+
+func TestOrderService_SearchOrders_NextPage(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	now := time.Now().UTC()
+	orders := []*models.Order{
+		{OrderUID: "o3", DateCreated: now},
+		{OrderUID: "o2", DateCreated: now.Add(-time.Minute)},
+		{OrderUID: "o1", DateCreated: now.Add(-2 * time.Minute)},
+	}
+
+	// сервис запрашивает на один заказ больше лимита
+	repo.On("SearchOrders", mock.Anything, models.OrderFilter{CustomerID: "c1", Limit: 3}).
+		Return(orders, nil).Once()
+
+	page, err := svc.SearchOrders(context.Background(), models.OrderFilter{CustomerID: "c1", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, orders[:2], page.Orders)
+
+	cursor, err := models.DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "o2", cursor.OrderUID)
+	assert.True(t, orders[1].DateCreated.Equal(cursor.DateCreated))
+
+	cache.AssertNotCalled(t, "Set", mock.Anything)
+}
+
+func TestOrderService_SearchOrders_LastPage(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	orders := []*models.Order{{OrderUID: "o1"}}
+
+	repo.On("SearchOrders", mock.Anything, models.OrderFilter{Limit: DefaultSearchLimit + 1}).
+		Return(orders, nil).Once()
+
+	page, err := svc.SearchOrders(context.Background(), models.OrderFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, orders, page.Orders)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestOrderService_SearchOrders_LimitClamped(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+
+	repo.On("SearchOrders", mock.Anything, models.OrderFilter{Limit: MaxSearchLimit + 1}).
+		Return([]*models.Order{}, nil).Once()
+
+	page, err := svc.SearchOrders(context.Background(), models.OrderFilter{Limit: 1000})
+	require.NoError(t, err)
+	assert.Empty(t, page.Orders)
+}
+
+func TestOrderService_SearchOrders_RepoError(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+
+	repo.On("SearchOrders", mock.Anything, mock.Anything).Return(nil, errors.New("db fail")).Once()
+
+	_, err := svc.SearchOrders(context.Background(), models.OrderFilter{})
+	require.Error(t, err)
+}
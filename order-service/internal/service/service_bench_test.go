@@ -73,7 +73,7 @@ func BenchmarkGetOrderByUID_CacheHit(b *testing.B) {
 
 	mockCache.On("Get", "bench-uid").Return(order, true)
 
-	svc := NewOrderService(mockRepo, mockCache, benchSvcLogger())
+	svc := NewOrderService(mockRepo, mockCache, benchSvcLogger(), ConflictIgnore)
 	ctx := context.Background()
 
 	b.ResetTimer()
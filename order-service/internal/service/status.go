@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"slices"
+	"time"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// transitions - допустимые переходы между статусами заказа.
+// cancelled и returned - конечные статусы
+var transitions = map[models.OrderStatus][]models.OrderStatus{
+	models.StatusCreated:   {models.StatusPaid, models.StatusCancelled},
+	models.StatusPaid:      {models.StatusAssembled, models.StatusCancelled},
+	models.StatusAssembled: {models.StatusShipped, models.StatusCancelled},
+	models.StatusShipped:   {models.StatusDelivered, models.StatusReturned},
+	models.StatusDelivered: {models.StatusReturned},
+}
+
+// CanTransition - разрешён ли переход заказа из статуса from в статус to
+func CanTransition(from, to models.OrderStatus) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// UpdateOrderStatus - применяет событие смены статуса, проверяя его по таблице переходов.
+// Повторное событие с текущим статусом заказа ничего не меняет
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, event *models.StatusEvent) error {
+	const op = "OrderService.UpdateOrderStatus"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("order_uid", event.OrderUID),
+		slog.String("status", string(event.Status)),
+	)
+
+	current, err := s.db.GetOrderStatus(ctx, event.OrderUID)
+	if err != nil {
+		log.Error("failed to get current order status", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if current == event.Status {
+		log.Debug("order already has this status, skipping")
+		return nil
+	}
+
+	if !CanTransition(current, event.Status) {
+		log.Warn("invalid status transition", slog.String("from", string(current)))
+		return fmt.Errorf("%s: %w: %s -> %s", op, ErrInvalidTransition, current, event.Status)
+	}
+
+	change := models.StatusChange{
+		From:      current,
+		To:        event.Status,
+		ChangedAt: event.ChangedAt,
+		Reason:    event.Reason,
+	}
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now()
+	}
+
+	if err = s.db.UpdateOrderStatus(ctx, event.OrderUID, current, change); err != nil {
+		log.Error("failed to update order status", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	//заказ в кеше может читаться параллельно, поэтому кладём копию, а не меняем его на месте
+	if order, ok := s.cache.Get(event.OrderUID); ok {
+		updated := *order
+		updated.Status = event.Status
+		s.cache.Set(&updated)
+	}
+	log.Info("order status updated", slog.String("from", string(current)))
+
+	return nil
+}
+
+// GetOrderHistory - возвращает историю статусов заказа
+func (s *OrderService) GetOrderHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	const op = "OrderService.GetOrderHistory"
+
+	history, err := s.db.GetStatusHistory(ctx, orderUID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			s.log.Error("failed to get status history from repository",
+				slog.String("op", op),
+				slog.String("order_uid", orderUID),
+				slog.Any("error", err),
+			)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
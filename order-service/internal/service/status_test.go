@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/service/mocks"
+)
+
+func TestCanTransition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		from, to models.OrderStatus
+		want     bool
+	}{
+		{models.StatusCreated, models.StatusPaid, true},
+		{models.StatusCreated, models.StatusCancelled, true},
+		{models.StatusCreated, models.StatusShipped, false},
+		{models.StatusPaid, models.StatusAssembled, true},
+		{models.StatusAssembled, models.StatusShipped, true},
+		{models.StatusShipped, models.StatusDelivered, true},
+		{models.StatusShipped, models.StatusCancelled, false},
+		{models.StatusDelivered, models.StatusReturned, true},
+		{models.StatusDelivered, models.StatusPaid, false},
+		{models.StatusCancelled, models.StatusPaid, false},
+		{models.StatusReturned, models.StatusDelivered, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CanTransition(tt.from, tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestOrderService_UpdateOrderStatus_Success(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	ctx := context.Background()
+	changedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	cached := &models.Order{OrderUID: "uid-1", Status: models.StatusCreated}
+
+	repo.On("GetOrderStatus", mock.Anything, "uid-1").Return(models.StatusCreated, nil).Once()
+	repo.On("UpdateOrderStatus", mock.Anything, "uid-1", models.StatusCreated, models.StatusChange{
+		From:      models.StatusCreated,
+		To:        models.StatusPaid,
+		ChangedAt: changedAt,
+	}).Return(nil).Once()
+	cache.On("Get", "uid-1").Return(cached, true).Once()
+	cache.On("Set", mock.MatchedBy(func(o *models.Order) bool {
+		return o.OrderUID == "uid-1" && o.Status == models.StatusPaid
+	})).Once()
+
+	err := svc.UpdateOrderStatus(ctx, &models.StatusEvent{
+		OrderUID:  "uid-1",
+		Status:    models.StatusPaid,
+		ChangedAt: changedAt,
+	})
+	require.NoError(t, err)
+
+	// закешированный заказ не меняется на месте
+	assert.Equal(t, models.StatusCreated, cached.Status)
+}
+
+func TestOrderService_UpdateOrderStatus_SameStatus(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+
+	repo.On("GetOrderStatus", mock.Anything, "uid-1").Return(models.StatusPaid, nil).Once()
+
+	err := svc.UpdateOrderStatus(context.Background(), &models.StatusEvent{OrderUID: "uid-1", Status: models.StatusPaid})
+	require.NoError(t, err)
+
+	repo.AssertNotCalled(t, "UpdateOrderStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOrderService_UpdateOrderStatus_InvalidTransition(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+	defer cache.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+
+	repo.On("GetOrderStatus", mock.Anything, "uid-1").Return(models.StatusCancelled, nil).Once()
+
+	err := svc.UpdateOrderStatus(context.Background(), &models.StatusEvent{OrderUID: "uid-1", Status: models.StatusShipped})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	repo.AssertNotCalled(t, "UpdateOrderStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "Set", mock.Anything)
+}
+
+func TestOrderService_UpdateOrderStatus_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+
+	repo.On("GetOrderStatus", mock.Anything, "missing").Return(models.OrderStatus(""), repository.ErrNotFound).Once()
+
+	err := svc.UpdateOrderStatus(context.Background(), &models.StatusEvent{OrderUID: "missing", Status: models.StatusPaid})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestOrderService_GetOrderHistory(t *testing.T) {
+	t.Parallel()
+
+	repo := new(mocks.OrderRepository)
+	cache := new(mocks.OrderCache)
+	defer repo.AssertExpectations(t)
+
+	svc := NewOrderService(repo, cache, testLogger(), ConflictIgnore)
+	history := []models.StatusChange{
+		{To: models.StatusCreated},
+		{From: models.StatusCreated, To: models.StatusPaid},
+	}
+
+	repo.On("GetStatusHistory", mock.Anything, "uid-1").Return(history, nil).Once()
+	repo.On("GetStatusHistory", mock.Anything, "missing").Return(nil, repository.ErrNotFound).Once()
+
+	got, err := svc.GetOrderHistory(context.Background(), "uid-1")
+	require.NoError(t, err)
+	assert.Equal(t, history, got)
+
+	_, err = svc.GetOrderHistory(context.Background(), "missing")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Коды ошибок валидации - стабильные значения для агрегации в DLQ и ответах API
+const (
+	CodeRequired          = "required"
+	CodeInvalidFormat     = "invalid_format"
+	CodeInvalidValue      = "invalid_value"
+	CodeMustBePositive    = "must_be_positive"
+	CodeMustBeNonNegative = "must_be_non_negative"
+	CodeMismatch          = "mismatch"
+	CodeOutOfRange        = "out_of_range"
+	CodeInconsistentTotal = "inconsistent_total"
+)
+
+// FieldError - ошибка валидации одного поля. Field - путь до поля в JSON заказа, например items[2].nm_id
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors - все ошибки валидации сообщения. errors.Is(err, ErrBadMessage) для неё истинно
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.String()
+	}
+	return ErrBadMessage.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrBadMessage
+}
+
+// JSON - ошибки в виде JSON-массива для заголовков DLQ
+func (e ValidationErrors) JSON() string {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	//сообщения содержат "<=" и ">", экранирование под HTML в заголовках не нужно
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		return "[]"
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (e *ValidationErrors) add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
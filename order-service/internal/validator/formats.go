@@ -0,0 +1,126 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// e164 - "+", код страны без ведущего нуля и не больше 15 цифр всего
+var e164 = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+
+// callingCodes - телефонные коды стран (ITU-T E.164) и ISO 3166-1 alpha-2 страны.
+// Для общих кодов (+1, +7) указана основная страна, уточнения - более длинными префиксами
+var callingCodes = map[string]string{
+	"1": "US", "1204": "CA", "1226": "CA", "1236": "CA", "1249": "CA", "1250": "CA", "1289": "CA",
+	"1306": "CA", "1343": "CA", "1365": "CA", "1403": "CA", "1416": "CA", "1418": "CA", "1431": "CA",
+	"1437": "CA", "1438": "CA", "1450": "CA", "1506": "CA", "1514": "CA", "1519": "CA", "1548": "CA",
+	"1579": "CA", "1581": "CA", "1587": "CA", "1604": "CA", "1613": "CA", "1639": "CA", "1647": "CA",
+	"1705": "CA", "1709": "CA", "1778": "CA", "1780": "CA", "1782": "CA", "1807": "CA", "1819": "CA",
+	"1825": "CA", "1867": "CA", "1873": "CA", "1902": "CA", "1905": "CA",
+	"7": "RU", "76": "KZ", "77": "KZ",
+	"20": "EG", "27": "ZA", "30": "GR", "31": "NL", "32": "BE", "33": "FR", "34": "ES", "36": "HU",
+	"39": "IT", "40": "RO", "41": "CH", "43": "AT", "44": "GB", "45": "DK", "46": "SE", "47": "NO",
+	"48": "PL", "49": "DE", "51": "PE", "52": "MX", "53": "CU", "54": "AR", "55": "BR", "56": "CL",
+	"57": "CO", "58": "VE", "60": "MY", "61": "AU", "62": "ID", "63": "PH", "64": "NZ", "65": "SG",
+	"66": "TH", "81": "JP", "82": "KR", "84": "VN", "86": "CN", "90": "TR", "91": "IN", "92": "PK",
+	"93": "AF", "94": "LK", "95": "MM", "98": "IR",
+	"212": "MA", "213": "DZ", "216": "TN", "234": "NG", "254": "KE",
+	"351": "PT", "353": "IE", "358": "FI", "359": "BG", "370": "LT", "371": "LV", "372": "EE",
+	"373": "MD", "374": "AM", "375": "BY", "380": "UA", "381": "RS", "385": "HR", "386": "SI",
+	"420": "CZ", "421": "SK", "852": "HK", "886": "TW",
+	"966": "SA", "971": "AE", "972": "IL", "992": "TJ", "993": "TM", "994": "AZ", "995": "GE",
+	"996": "KG", "998": "UZ",
+}
+
+// ValidPhone - номер в формате E.164. Код страны не обязан быть в callingCodes: список неполный
+func ValidPhone(phone string) bool {
+	return e164.MatchString(phone)
+}
+
+// PhoneCountry - определяет страну номера E.164 по самому длинному совпавшему коду.
+// Определение best-effort: ok=false для номеров не в E.164 и с кодом, которого нет в callingCodes
+func PhoneCountry(phone string) (country string, ok bool) {
+	if !ValidPhone(phone) {
+		return "", false
+	}
+	digits := phone[1:]
+	for n := min(4, len(digits)); n > 0; n-- {
+		if c, found := callingCodes[digits[:n]]; found {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// currencies - действующие коды валют ISO 4217
+var currencies = toSet(strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BRL BSD BTN BWP BYN BZD
+	CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD
+	GNF GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT
+	LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR
+	NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP
+	STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES VND VUV WST XAF XCD XOF
+	XPF YER ZAR ZMW ZWL
+`))
+
+// ValidCurrency - код валюты из ISO 4217, заглавными буквами
+func ValidCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// ValidLocale - корректный тег BCP 47 с известными языком, регионом и письменностью, например ru, en-US, zh-Hant-TW
+func ValidLocale(locale string) bool {
+	if locale == "" || strings.ContainsRune(locale, '_') {
+		return false
+	}
+	_, err := language.Parse(locale)
+	return err == nil
+}
+
+// postalCodes - форматы почтовых индексов по странам. Для стран не из списка проверяется только наличие индекса
+var postalCodes = map[string]*regexp.Regexp{
+	"RU": regexp.MustCompile(`^\d{6}$`),
+	"BY": regexp.MustCompile(`^\d{6}$`),
+	"KZ": regexp.MustCompile(`^(\d{6}|[A-Z]\d{2}[A-Z]\d[A-Z]\d)$`),
+	"KG": regexp.MustCompile(`^\d{6}$`),
+	"UZ": regexp.MustCompile(`^\d{6}$`),
+	"TJ": regexp.MustCompile(`^\d{6}$`),
+	"AM": regexp.MustCompile(`^\d{4}$`),
+	"GE": regexp.MustCompile(`^\d{4}$`),
+	"AZ": regexp.MustCompile(`^(AZ ?)?\d{4}$`),
+	"UA": regexp.MustCompile(`^\d{5}$`),
+	"IL": regexp.MustCompile(`^\d{7}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"TR": regexp.MustCompile(`^\d{5}$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+}
+
+// ValidPostalCode - индекс соответствует формату страны. Для стран без известного формата - true
+func ValidPostalCode(country, zip string) bool {
+	re, ok := postalCodes[country]
+	if !ok {
+		return true
+	}
+	return re.MatchString(zip)
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,324 @@
+package validator_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"order-service/internal/models"
+	"order-service/internal/validator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhoneCountry(t *testing.T) {
+	tests := []struct {
+		phone   string
+		country string
+		ok      bool
+	}{
+		{phone: "+79001234567", country: "RU", ok: true},
+		{phone: "+77011234567", country: "KZ", ok: true},
+		{phone: "+375291234567", country: "BY", ok: true},
+		{phone: "+9720000000", country: "IL", ok: true},
+		{phone: "+12025550123", country: "US", ok: true},
+		{phone: "+14165550123", country: "CA", ok: true},
+		{phone: "+442071234567", country: "GB", ok: true},
+		{phone: "+998901234567", country: "UZ", ok: true},
+		{phone: "89001234567"},       // без +
+		{phone: "+7 900 123-45-67"},  // с разделителями
+		{phone: "+0123456789"},       // код страны с нуля
+		{phone: "+7900"},             // слишком короткий
+		{phone: "+7900123456789012"}, // длиннее 15 цифр
+		{phone: "+2991234567"},       // неизвестный код (Гренландия не в списке)
+		{phone: "+79001234567\n"},    // перевод строки
+		{phone: "+７９００１２３４５６７"},      // полноширинные цифры
+		{phone: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phone, func(t *testing.T) {
+			country, ok := validator.PhoneCountry(tt.phone)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.country, country)
+		})
+	}
+}
+
+func TestValidPhone(t *testing.T) {
+	for _, phone := range []string{"+79001234567", "+35722123456", "+3545512345", "+2991234567"} {
+		assert.True(t, validator.ValidPhone(phone), phone)
+	}
+	for _, phone := range []string{"", "89001234567", "+7 900 123-45-67", "+0123456789", "+7900", "+7900123456789012"} {
+		assert.False(t, validator.ValidPhone(phone), phone)
+	}
+}
+
+func TestValidCurrency(t *testing.T) {
+	for _, code := range []string{"RUB", "USD", "EUR", "KZT", "BYN", "CNY", "ILS"} {
+		assert.True(t, validator.ValidCurrency(code), code)
+	}
+	for _, code := range []string{"", "rub", "RUR", "BYR", "US", "USDT", "XYZ", " USD"} {
+		assert.False(t, validator.ValidCurrency(code), code)
+	}
+}
+
+func TestValidLocale(t *testing.T) {
+	for _, locale := range []string{"en", "ru", "en-US", "ru-RU", "zh-Hant-TW", "kk", "sr-Latn"} {
+		assert.True(t, validator.ValidLocale(locale), locale)
+	}
+	for _, locale := range []string{"", "en_US", "english", "xx", "qq-RU", "e", "ru-", "-ru"} {
+		assert.False(t, validator.ValidLocale(locale), locale)
+	}
+}
+
+func TestValidPostalCode(t *testing.T) {
+	tests := []struct {
+		country string
+		zip     string
+		want    bool
+	}{
+		{country: "RU", zip: "123456", want: true},
+		{country: "RU", zip: "12345"},
+		{country: "RU", zip: "1234567"},
+		{country: "RU", zip: "12345a"},
+		{country: "KZ", zip: "050000", want: true},
+		{country: "KZ", zip: "A15E3C5", want: true},
+		{country: "IL", zip: "2639809", want: true},
+		{country: "IL", zip: "263980"},
+		{country: "US", zip: "90210", want: true},
+		{country: "US", zip: "90210-1234", want: true},
+		{country: "US", zip: "9021"},
+		{country: "CA", zip: "K1A 0B1", want: true},
+		{country: "GB", zip: "SW1A 1AA", want: true},
+		{country: "PL", zip: "00-950", want: true},
+		{country: "PL", zip: "00950"},
+		{country: "AM", zip: "0010", want: true},
+		//для стран без известного формата индекс не проверяется
+		{country: "AR", zip: "C1425", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country+"/"+tt.zip, func(t *testing.T) {
+			assert.Equal(t, tt.want, validator.ValidPostalCode(tt.country, tt.zip))
+		})
+	}
+}
+
+func TestValidate_Formats(t *testing.T) {
+	created := createValidOrder().DateCreated
+
+	tests := []struct {
+		name        string
+		modifyOrder func(*models.Order)
+		wantField   string
+		wantCode    string
+	}{
+		{
+			name:        "phone not in E.164",
+			modifyOrder: func(o *models.Order) { o.Delivery.Phone = "8 (900) 123-45-67" },
+			wantField:   "delivery.phone",
+			wantCode:    validator.CodeInvalidFormat,
+		},
+		{
+			name: "zip does not match phone country",
+			modifyOrder: func(o *models.Order) {
+				o.Delivery.Phone = "+79001234567"
+			},
+			wantField: "delivery.zip",
+			wantCode:  validator.CodeInvalidFormat,
+		},
+		{
+			name:        "unknown currency",
+			modifyOrder: func(o *models.Order) { o.Payment.Currency = "RUR" },
+			wantField:   "payment.currency",
+			wantCode:    validator.CodeInvalidValue,
+		},
+		{
+			name:        "lowercase currency",
+			modifyOrder: func(o *models.Order) { o.Payment.Currency = "usd" },
+			wantField:   "payment.currency",
+			wantCode:    validator.CodeInvalidValue,
+		},
+		{
+			name:        "malformed locale",
+			modifyOrder: func(o *models.Order) { o.Locale = "en_US" },
+			wantField:   "locale",
+			wantCode:    validator.CodeInvalidValue,
+		},
+		{
+			name:        "unknown locale",
+			modifyOrder: func(o *models.Order) { o.Locale = "xx" },
+			wantField:   "locale",
+			wantCode:    validator.CodeInvalidValue,
+		},
+		{
+			name:        "payment long before order creation",
+			modifyOrder: func(o *models.Order) { o.Payment.PaymentDT = created.Add(-25 * time.Hour).Unix() },
+			wantField:   "payment.payment_dt",
+			wantCode:    validator.CodeOutOfRange,
+		},
+		{
+			name:        "payment long after order creation",
+			modifyOrder: func(o *models.Order) { o.Payment.PaymentDT = created.Add(31 * 24 * time.Hour).Unix() },
+			wantField:   "payment.payment_dt",
+			wantCode:    validator.CodeOutOfRange,
+		},
+		{
+			name:        "payment in milliseconds",
+			modifyOrder: func(o *models.Order) { o.Payment.PaymentDT = created.UnixMilli() },
+			wantField:   "payment.payment_dt",
+			wantCode:    validator.CodeOutOfRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := createValidOrder()
+			tt.modifyOrder(order)
+
+			verrs := fieldErrors(t, validator.Validate(nil, order))
+			assert.Len(t, verrs, 1)
+			assert.Equal(t, tt.wantField, verrs[0].Field)
+			assert.Equal(t, tt.wantCode, verrs[0].Code)
+		})
+	}
+}
+
+func TestValidate_FormatsValid(t *testing.T) {
+	tests := []struct {
+		name        string
+		modifyOrder func(*models.Order)
+	}{
+		{name: "empty locale", modifyOrder: func(o *models.Order) { o.Locale = "" }},
+		{name: "locale with region", modifyOrder: func(o *models.Order) { o.Locale = "ru-RU" }},
+		{
+			name: "russian phone and zip",
+			modifyOrder: func(o *models.Order) {
+				o.Delivery.Phone = "+79001234567"
+				o.Delivery.Zip = "123456"
+				o.Payment.Currency = "RUB"
+			},
+		},
+		{
+			//страна не определяется, поэтому индекс любого формата
+			name: "phone with country code missing from the list",
+			modifyOrder: func(o *models.Order) {
+				o.Delivery.Phone = "+35722123456"
+				o.Delivery.Zip = "1010"
+			},
+		},
+		{
+			name:        "phone with another unlisted country code",
+			modifyOrder: func(o *models.Order) { o.Delivery.Phone = "+3545512345" },
+		},
+		{
+			name:        "payment right before creation",
+			modifyOrder: func(o *models.Order) { o.Payment.PaymentDT = o.DateCreated.Add(-time.Hour).Unix() },
+		},
+		{
+			name:        "payment a week after creation",
+			modifyOrder: func(o *models.Order) { o.Payment.PaymentDT = o.DateCreated.Add(7 * 24 * time.Hour).Unix() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := createValidOrder()
+			tt.modifyOrder(order)
+			assert.NoError(t, validator.Validate(nil, order))
+		})
+	}
+}
+
+var (
+	countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+	digitsOnly  = regexp.MustCompile(`^\d+$`)
+)
+
+func FuzzPhoneCountry(f *testing.F) {
+	for _, seed := range []string{"+79001234567", "+9720000000", "+14165550123", "+0", "", "+", "+7９", "+1234567890123456"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, phone string) {
+		country, ok := validator.PhoneCountry(phone)
+		if !ok {
+			if country != "" {
+				t.Fatalf("country %q returned for invalid phone %q", country, phone)
+			}
+			return
+		}
+		if !countryCode.MatchString(country) {
+			t.Fatalf("invalid country %q for %q", country, phone)
+		}
+		if len(phone) < 8 || len(phone) > 16 || phone[0] != '+' || phone[1] == '0' {
+			t.Fatalf("phone %q accepted", phone)
+		}
+		for _, r := range phone[1:] {
+			if r < '0' || r > '9' {
+				t.Fatalf("phone %q with non-digit accepted", phone)
+			}
+		}
+	})
+}
+
+func FuzzValidLocale(f *testing.F) {
+	for _, seed := range []string{"en", "ru-RU", "zh-Hant-TW", "en_US", "x-private", "und", "-", "en-US-u-ca-gregory"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, locale string) {
+		if validator.ValidLocale(locale) && !utf8.ValidString(locale) {
+			t.Fatalf("invalid utf-8 locale %q accepted", locale)
+		}
+	})
+}
+
+func FuzzValidPostalCode(f *testing.F) {
+	for _, seed := range [][2]string{{"RU", "123456"}, {"KZ", "A15E3C5"}, {"GB", "SW1A 1AA"}, {"", ""}, {"US", "90210-"}} {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, country, zip string) {
+		if validator.ValidPostalCode("RU", zip) && (len(zip) != 6 || !digitsOnly.MatchString(zip)) {
+			t.Fatalf("russian zip %q accepted", zip)
+		}
+		validator.ValidPostalCode(country, zip)
+	})
+}
+
+// FuzzValidate - произвольные значения проверяемых полей не роняют валидатор,
+// а каждая ошибка содержит путь до поля, код и текст
+func FuzzValidate(f *testing.F) {
+	f.Add("+9720000000", "2639809", "USD", "en", int64(1637907727), 30)
+	f.Add("+79001234567", "123456", "RUB", "ru-RU", int64(0), 0)
+	f.Add("", "", "", "", int64(-1), -5)
+	f.Add("+", "ABC DEF", "usd", "en_US", int64(1<<62), 200)
+
+	f.Fuzz(func(t *testing.T, phone, zip, currency, locale string, paymentDT int64, sale int) {
+		order := createValidOrder()
+		order.Delivery.Phone = phone
+		order.Delivery.Zip = zip
+		order.Payment.Currency = currency
+		order.Locale = locale
+		order.Payment.PaymentDT = paymentDT
+		order.Items[0].Sale = sale
+
+		err := validator.Validate(nil, order)
+		if err == nil {
+			return
+		}
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) || !errors.Is(err, validator.ErrBadMessage) {
+			t.Fatalf("unexpected error type %T: %v", err, err)
+		}
+		for _, fe := range verrs {
+			if fe.Field == "" || fe.Code == "" || fe.Message == "" {
+				t.Fatalf("incomplete field error %+v", fe)
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// LoadRules - читает профили правил из YAML/JSON файла
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+
+	rs, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rs, nil
+}
+
+// WatchRules - раз в interval проверяет время изменения и размер файла и перечитывает правила.
+// Если новый файл не разбирается, валидатор продолжает работать с прежними правилами.
+// Блокирует до отмены ctx
+func (v *Validator) WatchRules(ctx context.Context, path string, interval time.Duration, log *slog.Logger) {
+	//размер сравнивается вместе с временем изменения: две записи подряд могут попасть в одну метку времени.
+	//Первая проверка всегда перечитывает файл - он мог измениться после загрузки правил при старте
+	var modTime time.Time
+	size := int64(-1)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Error("failed to stat validation rules", slog.String("path", path), slog.Any("error", err))
+			continue
+		}
+		if info.ModTime().Equal(modTime) && info.Size() == size {
+			continue
+		}
+		modTime, size = info.ModTime(), info.Size()
+
+		rs, err := LoadRules(path)
+		if err != nil {
+			log.Error("failed to reload validation rules, keeping previous", slog.Any("error", err))
+			continue
+		}
+		v.SetRules(rs)
+		log.Info("validation rules reloaded", slog.String("path", path), slog.Int("profiles", len(rs.profiles)+1))
+	}
+}
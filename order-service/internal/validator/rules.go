@@ -0,0 +1,530 @@
+package validator
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/mail"
+	"order-service/internal/models"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfile - профиль, который применяется, если заказ не подошёл ни под один другой
+const DefaultProfile = "default"
+
+//go:embed default_rules.yaml
+var defaultRulesYAML []byte
+
+// RulesFile - файл с профилями правил. JSON тоже читается: он является подмножеством YAML
+type RulesFile struct {
+	Profiles []ProfileSpec `yaml:"profiles"`
+}
+
+// ProfileSpec - набор правил для заказов с заданными entry/delivery_service.
+// Пустой список в Match не ограничивает выбор по этому полю
+type ProfileSpec struct {
+	Name  string `yaml:"name"`
+	Match struct {
+		Entry           []string `yaml:"entry"`
+		DeliveryService []string `yaml:"delivery_service"`
+	} `yaml:"match"`
+	//профиль наследует правила родителя, кроме правил для полей из Skip
+	Extends string     `yaml:"extends"`
+	Skip    []string   `yaml:"skip"`
+	Rules   []RuleSpec `yaml:"rules"`
+}
+
+// RuleSpec - одна проверка одного поля. Поле задаётся путём из JSON-тегов заказа,
+// для позиций - items[].nm_id. Должна быть задана ровно одна проверка:
+// required, regex, min/max, enum, equals (другое поле заказа), format (см. formats) или within.
+// regex, enum, format и within не проверяют пустые значения - для этого есть required
+type RuleSpec struct {
+	Field    string   `yaml:"field"`
+	Required bool     `yaml:"required"`
+	Regex    string   `yaml:"regex"`
+	Min      *int64   `yaml:"min"`
+	Max      *int64   `yaml:"max"`
+	Enum     []string `yaml:"enum"`
+	Equals   string   `yaml:"equals"`
+	Format   string   `yaml:"format"`
+	Within   *Within  `yaml:"within"`
+	//код и текст ошибки; по умолчанию выбираются по виду проверки
+	Code    string `yaml:"code"`
+	Message string `yaml:"message"`
+}
+
+// Within - время в поле (unix-время или дата) не раньше Before до и не позже After после поля Of
+type Within struct {
+	Of     string        `yaml:"of"`
+	Before time.Duration `yaml:"before"`
+	After  time.Duration `yaml:"after"`
+}
+
+// format - именованная проверка строкового поля. valid получает весь заказ для проверок,
+// зависящих от других полей
+type format struct {
+	code, message string
+	valid         func(s string, root reflect.Value) bool
+}
+
+var formats = map[string]format{
+	"email": {CodeInvalidFormat, "has invalid format", func(s string, _ reflect.Value) bool {
+		return isValidEmail(s)
+	}},
+	"order_status": {CodeInvalidValue, "is invalid", func(s string, _ reflect.Value) bool {
+		return models.OrderStatus(s).Valid()
+	}},
+	"phone": {CodeInvalidFormat, "must be in E.164 format", func(s string, _ reflect.Value) bool {
+		return ValidPhone(s)
+	}},
+	"currency": {CodeInvalidValue, "must be an ISO 4217 currency code", func(s string, _ reflect.Value) bool {
+		return ValidCurrency(s)
+	}},
+	"locale": {CodeInvalidValue, "must be a known BCP 47 language tag", func(s string, _ reflect.Value) bool {
+		return ValidLocale(s)
+	}},
+	//страна определяется по телефону получателя; если она не определилась (не E.164 или код не из списка),
+	//индекс не проверяется
+	"postal_code": {CodeInvalidFormat, "does not match the postal code format of the delivery.phone country",
+		func(s string, root reflect.Value) bool {
+			country, ok := PhoneCountry(root.Addr().Interface().(*models.Order).Delivery.Phone)
+			return !ok || ValidPostalCode(country, s)
+		}},
+}
+
+// RuleSet - скомпилированные профили правил. Безопасен для одновременного использования
+type RuleSet struct {
+	profiles []*profile
+	def      *profile
+}
+
+type profile struct {
+	name            string
+	entry           []string
+	deliveryService []string
+	rules           []*rule
+}
+
+type rule struct {
+	field   *fieldPath
+	code    string
+	message string
+	check   func(v reflect.Value, root reflect.Value) bool
+}
+
+var defaultRules = mustParseRules(defaultRulesYAML, nil)
+
+// DefaultRules - правила, зашитые в сервис
+func DefaultRules() *RuleSet {
+	return defaultRules
+}
+
+// ParseRules - разбирает и компилирует профили из YAML/JSON. Профиль default, если он не задан в файле,
+// берётся из встроенных правил
+func ParseRules(data []byte) (*RuleSet, error) {
+	return parseRules(data, defaultRules)
+}
+
+func mustParseRules(data []byte, base *RuleSet) *RuleSet {
+	rs, err := parseRules(data, base)
+	if err != nil {
+		panic(fmt.Sprintf("validator: invalid built-in rules: %v", err))
+	}
+	return rs
+}
+
+func parseRules(data []byte, base *RuleSet) (*RuleSet, error) {
+	var file RulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	//опечатка в имени проверки не должна молча отключать правило
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+
+	specs := make(map[string]*ProfileSpec, len(file.Profiles))
+	for i := range file.Profiles {
+		p := &file.Profiles[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("profiles[%d]: name is required", i)
+		}
+		if _, ok := specs[p.Name]; ok {
+			return nil, fmt.Errorf("profile %q: duplicate name", p.Name)
+		}
+		specs[p.Name] = p
+	}
+
+	rs := &RuleSet{}
+	compiled := make(map[string]*profile, len(specs))
+	if _, ok := specs[DefaultProfile]; !ok {
+		if base == nil {
+			return nil, fmt.Errorf("profile %q is required", DefaultProfile)
+		}
+		compiled[DefaultProfile] = base.def
+	}
+
+	var compile func(name string, chain []string) (*profile, error)
+	compile = func(name string, chain []string) (*profile, error) {
+		if p, ok := compiled[name]; ok {
+			return p, nil
+		}
+		spec, ok := specs[name]
+		if !ok {
+			return nil, fmt.Errorf("profile %q: not found", name)
+		}
+		if slices.Contains(chain, name) {
+			return nil, fmt.Errorf("profile %q: extends cycle %s", name, strings.Join(append(chain, name), " -> "))
+		}
+
+		p := &profile{name: name, entry: spec.Match.Entry, deliveryService: spec.Match.DeliveryService}
+		if spec.Extends != "" {
+			parent, err := compile(spec.Extends, append(chain, name))
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range parent.rules {
+				if !slices.Contains(spec.Skip, r.field.name) {
+					p.rules = append(p.rules, r)
+				}
+			}
+		}
+		for i, rspec := range spec.Rules {
+			r, err := compileRule(rspec)
+			if err != nil {
+				return nil, fmt.Errorf("profile %q: rules[%d]: %w", name, i, err)
+			}
+			p.rules = append(p.rules, r)
+		}
+
+		compiled[name] = p
+		return p, nil
+	}
+
+	for _, spec := range file.Profiles {
+		p, err := compile(spec.Name, nil)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Name != DefaultProfile {
+			rs.profiles = append(rs.profiles, p)
+		}
+	}
+	rs.def = compiled[DefaultProfile]
+
+	return rs, nil
+}
+
+// profileFor - первый подходящий профиль в порядке объявления, иначе default.
+// Профили без match служат только базой для extends
+func (rs *RuleSet) profileFor(o *models.Order) *profile {
+	for _, p := range rs.profiles {
+		if len(p.entry) == 0 && len(p.deliveryService) == 0 {
+			continue
+		}
+		if len(p.entry) > 0 && !slices.Contains(p.entry, o.Entry) {
+			continue
+		}
+		if len(p.deliveryService) > 0 && !slices.Contains(p.deliveryService, o.DeliveryService) {
+			continue
+		}
+		return p
+	}
+	return rs.def
+}
+
+func (p *profile) validate(errs *ValidationErrors, o *models.Order) {
+	root := reflect.ValueOf(o).Elem()
+	for _, r := range p.rules {
+		fp := r.field
+		v := root.FieldByIndex(fp.head)
+		if !fp.slice {
+			if !r.check(v, root) {
+				errs.add(fp.name, r.code, r.message)
+			}
+			continue
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !r.check(v.Index(i).FieldByIndex(fp.tail), root) {
+				errs.add(fp.pathAt(i), r.code, r.message)
+			}
+		}
+	}
+}
+
+func compileRule(spec RuleSpec) (*rule, error) {
+	fp, err := parseFieldPath(spec.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := 0
+	for _, set := range []bool{
+		spec.Required, spec.Regex != "", spec.Min != nil || spec.Max != nil,
+		len(spec.Enum) > 0, spec.Equals != "", spec.Format != "", spec.Within != nil,
+	} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("%s: exactly one check must be set", spec.Field)
+	}
+
+	r := &rule{field: fp}
+	switch {
+	case spec.Required:
+		r.code, r.message = CodeRequired, "is required"
+		r.check = func(v, _ reflect.Value) bool { return !isEmpty(v, fp.kind) }
+
+	case spec.Regex != "":
+		if fp.kind != kindString {
+			return nil, fmt.Errorf("%s: regex requires a string field", spec.Field)
+		}
+		re, err := regexp.Compile(spec.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec.Field, err)
+		}
+		r.code, r.message = CodeInvalidFormat, "has invalid format"
+		r.check = func(v, _ reflect.Value) bool { return v.String() == "" || re.MatchString(v.String()) }
+
+	case spec.Min != nil || spec.Max != nil:
+		if fp.kind != kindInt {
+			return nil, fmt.Errorf("%s: min/max require an integer field", spec.Field)
+		}
+		lo, hi := spec.Min, spec.Max
+		r.code, r.message = CodeOutOfRange, rangeMessage(lo, hi)
+		r.check = func(v, _ reflect.Value) bool {
+			n := v.Int()
+			return (lo == nil || n >= *lo) && (hi == nil || n <= *hi)
+		}
+
+	case len(spec.Enum) > 0:
+		if fp.kind != kindString {
+			return nil, fmt.Errorf("%s: enum requires a string field", spec.Field)
+		}
+		values := spec.Enum
+		r.code, r.message = CodeInvalidValue, "must be one of "+strings.Join(values, ", ")
+		r.check = func(v, _ reflect.Value) bool { return v.String() == "" || slices.Contains(values, v.String()) }
+
+	case spec.Equals != "":
+		other, err := parseFieldPath(spec.Equals)
+		if err != nil {
+			return nil, err
+		}
+		if other.slice {
+			return nil, fmt.Errorf("%s: equals must reference an order-level field", spec.Field)
+		}
+		if other.kind != fp.kind {
+			return nil, fmt.Errorf("%s: equals must reference a field of the same type", spec.Field)
+		}
+		r.code, r.message = CodeMismatch, "must equal "+spec.Equals
+		r.check = func(v, root reflect.Value) bool { return equal(v, other.value(root), fp.kind) }
+
+	case spec.Format != "":
+		if fp.kind != kindString {
+			return nil, fmt.Errorf("%s: format requires a string field", spec.Field)
+		}
+		f, ok := formats[spec.Format]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown format %q", spec.Field, spec.Format)
+		}
+		r.code, r.message = f.code, f.message
+		r.check = func(v, root reflect.Value) bool { return v.String() == "" || f.valid(v.String(), root) }
+
+	case spec.Within != nil:
+		if fp.kind != kindInt && fp.kind != kindTime {
+			return nil, fmt.Errorf("%s: within requires a unix time or time field", spec.Field)
+		}
+		of, err := parseFieldPath(spec.Within.Of)
+		if err != nil {
+			return nil, err
+		}
+		if of.slice || of.kind != kindTime {
+			return nil, fmt.Errorf("%s: within.of must reference an order-level time field", spec.Field)
+		}
+		before, after := spec.Within.Before, spec.Within.After
+		r.code, r.message = CodeOutOfRange, fmt.Sprintf("must be within %s before and %s after %s",
+			formatDuration(before), formatDuration(after), spec.Within.Of)
+		r.check = func(v, root reflect.Value) bool {
+			ref := timeOf(of.value(root))
+			var t time.Time
+			if fp.kind == kindInt {
+				if v.Int() == 0 {
+					return true
+				}
+				t = time.Unix(v.Int(), 0)
+			} else {
+				t = *timeOf(v)
+			}
+			if t.IsZero() || ref.IsZero() {
+				return true
+			}
+			return !t.Before(ref.Add(-before)) && !t.After(ref.Add(after))
+		}
+	}
+
+	if spec.Code != "" {
+		r.code = spec.Code
+	}
+	if spec.Message != "" {
+		r.message = spec.Message
+	}
+	return r, nil
+}
+
+// formatDuration - 24h вместо 24h0m0s
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func rangeMessage(lo, hi *int64) string {
+	switch {
+	case lo != nil && hi != nil:
+		return fmt.Sprintf("must be between %d and %d", *lo, *hi)
+	case lo != nil:
+		return fmt.Sprintf("must be >= %d", *lo)
+	default:
+		return fmt.Sprintf("must be <= %d", *hi)
+	}
+}
+
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindInt
+	kindTime
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fieldPath - путь до поля заказа, разрешённый в индексы полей структур.
+// Для items[].x head - путь до среза, tail - путь внутри элемента
+type fieldPath struct {
+	name  string
+	head  []int
+	slice bool
+	tail  []int
+	kind  valueKind
+	//имена для сообщений об ошибках: items и .nm_id
+	headName, tailName string
+}
+
+func parseFieldPath(path string) (*fieldPath, error) {
+	if path == "" {
+		return nil, errors.New("field is required")
+	}
+
+	fp := &fieldPath{name: path}
+	t := reflect.TypeOf(models.Order{})
+	for _, seg := range strings.Split(path, ".") {
+		name, isSlice := strings.CutSuffix(seg, "[]")
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s: %s is not an object", path, name)
+		}
+		f, ok := fieldByJSONName(t, name)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown field %s", path, name)
+		}
+		t = f.Type
+
+		if fp.slice {
+			fp.tail = append(fp.tail, f.Index...)
+			fp.tailName += "." + name
+		} else {
+			fp.head = append(fp.head, f.Index...)
+			if fp.headName != "" {
+				fp.headName += "."
+			}
+			fp.headName += name
+		}
+
+		if isSlice {
+			if fp.slice || t.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%s: %s is not a list or nested lists are used", path, name)
+			}
+			fp.slice = true
+			t = t.Elem()
+		}
+	}
+
+	switch {
+	case t == timeType:
+		fp.kind = kindTime
+	case t.Kind() == reflect.String:
+		fp.kind = kindString
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		fp.kind = kindInt
+	default:
+		return nil, fmt.Errorf("%s: unsupported field type %s", path, t)
+	}
+	return fp, nil
+}
+
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// value - значение поля без среза в пути
+func (fp *fieldPath) value(root reflect.Value) reflect.Value {
+	return root.FieldByIndex(fp.head)
+}
+
+// pathAt - путь до поля i-го элемента для сообщения об ошибке, например items[2].nm_id
+func (fp *fieldPath) pathAt(i int) string {
+	return fp.headName + "[" + strconv.Itoa(i) + "]" + fp.tailName
+}
+
+func isEmpty(v reflect.Value, kind valueKind) bool {
+	switch kind {
+	case kindString:
+		return strings.TrimSpace(v.String()) == ""
+	case kindTime:
+		return timeOf(v).IsZero()
+	default:
+		return v.IsZero()
+	}
+}
+
+func equal(a, b reflect.Value, kind valueKind) bool {
+	switch kind {
+	case kindString:
+		return a.String() == b.String()
+	case kindTime:
+		return timeOf(a).Equal(*timeOf(b))
+	default:
+		return a.Int() == b.Int()
+	}
+}
+
+// timeOf - значение time.Time без копирования в interface{}: поля заказа адресуемы
+func timeOf(v reflect.Value) *time.Time {
+	return v.Addr().Interface().(*time.Time)
+}
+
+func isValidEmail(email string) bool {
+	_, err := mail.ParseAddress(email)
+	return err == nil
+}
@@ -0,0 +1,272 @@
+package validator_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/validator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustRules(t *testing.T, yaml string) *validator.RuleSet {
+	t.Helper()
+	rs, err := validator.ParseRules([]byte(yaml))
+	require.NoError(t, err)
+	return rs
+}
+
+func fieldErrors(t *testing.T, err error) validator.ValidationErrors {
+	t.Helper()
+	var verrs validator.ValidationErrors
+	require.True(t, errors.As(err, &verrs), "expected ValidationErrors, got %v", err)
+	return verrs
+}
+
+func TestRules_ExampleFile(t *testing.T) {
+	rs, err := validator.LoadRules("../../validation_rules.example.yaml")
+	require.NoError(t, err)
+	v := validator.New(validator.Options{Rules: rs})
+
+	//заказ meest проверяется профилем default
+	assert.NoError(t, v.Validate(nil, createValidOrder()))
+
+	order := createValidOrder()
+	order.DeliveryService = "russian-post"
+	order.Delivery.Zip = "2639809"
+	assert.Equal(t, validator.ValidationErrors{{
+		Field: "delivery.zip", Code: validator.CodeInvalidFormat, Message: "must be a 6-digit russian zip",
+	}}, fieldErrors(t, v.Validate(nil, order)))
+
+	order.Delivery.Zip = "123456"
+	assert.NoError(t, v.Validate(nil, order))
+
+	order = createValidOrder()
+	order.DeliveryService = "wb-pickup"
+	order.Delivery.Region = ""
+	order.Payment.Currency = "RUB"
+	assert.NoError(t, v.Validate(nil, order))
+
+	order.Payment.Currency = "USD"
+	assert.Equal(t, validator.ValidationErrors{{
+		Field: "payment.currency", Code: validator.CodeInvalidValue, Message: "must be one of RUB",
+	}}, fieldErrors(t, v.Validate(nil, order)))
+
+	//тот же delivery_service с другим entry - профиль default, регион обязателен
+	order.Entry = "WBRU"
+	order.Payment.Currency = "RUB"
+	assert.Equal(t, validator.ValidationErrors{{
+		Field: "delivery.region", Code: validator.CodeRequired, Message: "is required",
+	}}, fieldErrors(t, v.Validate(nil, order)))
+}
+
+func TestRules_Checks(t *testing.T) {
+	rs := mustRules(t, `
+profiles:
+  - name: default
+    rules:
+      - {field: "items[].sale", min: 0, max: 90}
+      - {field: delivery.phone, regex: '^\+\d{10,15}$'}
+      - {field: "items[].brand", equals: delivery.name, code: brand_mismatch}
+      - {field: date_created, required: true}
+`)
+	v := validator.New(validator.Options{Rules: rs})
+
+	order := createValidOrder()
+	order.Items = append(order.Items, order.Items[0])
+	order.Items[1].Sale = 95
+	order.Items[1].TotalPrice = 23
+	order.Payment.GoodsTotal = 340
+	order.Payment.Amount = 1840
+	order.Delivery.Phone = "8-800-555-35-35"
+	order.Delivery.Name = "Vivienne Sabo"
+	order.Items[0].Brand = "Other"
+	order.DateCreated = time.Time{}
+
+	assert.Equal(t, validator.ValidationErrors{
+		{Field: "items[1].sale", Code: validator.CodeOutOfRange, Message: "must be between 0 and 90"},
+		{Field: "delivery.phone", Code: validator.CodeInvalidFormat, Message: "has invalid format"},
+		{Field: "items[0].brand", Code: "brand_mismatch", Message: "must equal delivery.name"},
+		{Field: "date_created", Code: validator.CodeRequired, Message: "is required"},
+	}, fieldErrors(t, v.Validate(nil, order)))
+}
+
+func TestRules_ProfileOverridesDefault(t *testing.T) {
+	//default из файла заменяет встроенный: остаётся только одно правило
+	rs := mustRules(t, `
+profiles:
+  - name: default
+    rules:
+      - {field: order_uid, required: true}
+`)
+	v := validator.New(validator.Options{Rules: rs})
+
+	order := createValidOrder()
+	order.Delivery = models.Delivery{}
+	assert.NoError(t, v.Validate(nil, order))
+
+	order.OrderUID = " "
+	order.Payment.Transaction = " "
+	assert.Equal(t, validator.ValidationErrors{{
+		Field: "order_uid", Code: validator.CodeRequired, Message: "is required",
+	}}, fieldErrors(t, v.Validate(nil, order)))
+}
+
+func TestRules_FirstMatchingProfileWins(t *testing.T) {
+	rs := mustRules(t, `
+profiles:
+  - name: base
+    rules:
+      - {field: delivery.city, enum: [Moscow]}
+  - name: meest
+    extends: base
+    match: {delivery_service: [meest]}
+  - name: meest-wbil
+    match: {entry: [WBIL], delivery_service: [meest]}
+`)
+	v := validator.New(validator.Options{Rules: rs})
+
+	order := createValidOrder()
+	verrs := fieldErrors(t, v.Validate(nil, order))
+	assert.Equal(t, "delivery.city", verrs[0].Field)
+
+	//профиль без match выбран быть не может
+	order.DeliveryService = "cdek"
+	assert.NoError(t, v.Validate(nil, order))
+}
+
+func TestRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			yaml:    `{profiles: [{name: default, rules: [{field: delivery.street, required: true}]}]}`,
+			wantErr: "unknown field street",
+		},
+		{
+			name:    "unknown check",
+			yaml:    `{profiles: [{name: default, rules: [{field: order_uid, requried: true}]}]}`,
+			wantErr: "field requried not found",
+		},
+		{
+			name:    "two checks in one rule",
+			yaml:    `{profiles: [{name: default, rules: [{field: order_uid, required: true, regex: x}]}]}`,
+			wantErr: "exactly one check must be set",
+		},
+		{
+			name:    "no checks",
+			yaml:    `{profiles: [{name: default, rules: [{field: order_uid}]}]}`,
+			wantErr: "exactly one check must be set",
+		},
+		{
+			name:    "regex on integer",
+			yaml:    `{profiles: [{name: default, rules: [{field: payment.amount, regex: x}]}]}`,
+			wantErr: "regex requires a string field",
+		},
+		{
+			name:    "range on string",
+			yaml:    `{profiles: [{name: default, rules: [{field: entry, min: 1}]}]}`,
+			wantErr: "min/max require an integer field",
+		},
+		{
+			name:    "bad regex",
+			yaml:    `{profiles: [{name: default, rules: [{field: entry, regex: "("}]}]}`,
+			wantErr: "missing closing )",
+		},
+		{
+			name:    "equals other type",
+			yaml:    `{profiles: [{name: default, rules: [{field: entry, equals: payment.amount}]}]}`,
+			wantErr: "same type",
+		},
+		{
+			name:    "equals item field",
+			yaml:    `{profiles: [{name: default, rules: [{field: entry, equals: "items[].rid"}]}]}`,
+			wantErr: "order-level field",
+		},
+		{
+			name:    "unknown format",
+			yaml:    `{profiles: [{name: default, rules: [{field: entry, format: uuid}]}]}`,
+			wantErr: `unknown format "uuid"`,
+		},
+		{
+			name:    "list index on object",
+			yaml:    `{profiles: [{name: default, rules: [{field: "delivery[].zip", required: true}]}]}`,
+			wantErr: "is not a list",
+		},
+		{
+			name:    "missing parent",
+			yaml:    `{profiles: [{name: a, extends: b}]}`,
+			wantErr: `profile "b": not found`,
+		},
+		{
+			name:    "extends cycle",
+			yaml:    `{profiles: [{name: a, extends: b}, {name: b, extends: a}]}`,
+			wantErr: "extends cycle a -> b -> a",
+		},
+		{
+			name:    "duplicate profile",
+			yaml:    `{profiles: [{name: a}, {name: a}]}`,
+			wantErr: "duplicate name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ParseRules([]byte(tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestRules_JSON(t *testing.T) {
+	rs := mustRules(t, `{"profiles": [{"name": "default", "rules": [{"field": "delivery.zip", "regex": "^\\d{6}$"}]}]}`)
+	v := validator.New(validator.Options{Rules: rs})
+
+	verrs := fieldErrors(t, v.Validate(nil, createValidOrder()))
+	assert.Equal(t, "delivery.zip", verrs[0].Field)
+}
+
+func TestValidator_WatchRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`{profiles: [{name: default}]}`), 0o644))
+
+	rs, err := validator.LoadRules(path)
+	require.NoError(t, err)
+	v := validator.New(validator.Options{Rules: rs})
+
+	order := createValidOrder()
+	order.Delivery.Zip = "2639809"
+	require.NoError(t, v.Validate(nil, order))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		v.WatchRules(ctx, path, time.Millisecond, createTestLogger())
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.NoError(t, os.WriteFile(path,
+		[]byte(`{profiles: [{name: default, rules: [{field: delivery.zip, regex: '^\d{6}$'}]}]}`), 0o644))
+	require.Eventually(t, func() bool { return v.Validate(nil, order) != nil }, 5*time.Second, time.Millisecond)
+
+	//сломанный файл не применяется, работают прежние правила
+	require.NoError(t, os.WriteFile(path, []byte(`{profiles: [{name: default, rules: [{field: nope}]}]}`), 0o644))
+	time.Sleep(20 * time.Millisecond)
+	assert.Error(t, v.Validate(nil, order))
+	order.Delivery.Zip = "123456"
+	assert.NoError(t, v.Validate(nil, order))
+}
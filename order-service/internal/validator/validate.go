@@ -4,143 +4,166 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"net/mail"
+	"math"
 	"order-service/internal/models"
 	"strings"
+	"sync/atomic"
 )
 
 var ErrBadMessage = errors.New("bad_message")
 
+// Mode - что делать с нарушением финансовых инвариантов заказа
+type Mode string
+
+const (
+	// ModeStrict - заказ с расхождением в суммах отклоняется
+	ModeStrict Mode = "strict"
+	// ModeLenient - расхождение пишется в лог, заказ принимается
+	ModeLenient Mode = "lenient"
+)
+
+// ParseMode - разбирает режим из конфига; пустая строка - strict
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return ModeStrict, nil
+	case ModeStrict, ModeLenient:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown validation mode %q", s)
+}
+
+// Options - настройки валидатора
+type Options struct {
+	Mode Mode
+	// допустимое расхождение сумм в минимальных единицах валюты
+	Tolerance int
+	// профили правил для полей заказа; nil - встроенные правила
+	Rules *RuleSet
+}
+
+var DefaultOptions = Options{Mode: ModeStrict}
+
+type Validator struct {
+	opts  Options
+	rules atomic.Pointer[RuleSet]
+}
+
+func New(opts Options) *Validator {
+	if opts.Mode == "" {
+		opts.Mode = ModeStrict
+	}
+	v := &Validator{opts: opts}
+	v.SetRules(opts.Rules)
+	return v
+}
+
+// SetRules - подменяет профили правил; заказы, которые уже проверяются, дорабатывают со старыми
+func (v *Validator) SetRules(rs *RuleSet) {
+	if rs == nil {
+		rs = DefaultRules()
+	}
+	v.rules.Store(rs)
+}
+
+var defaultValidator = New(DefaultOptions)
+
+// Validate - проверяет заказ с настройками по умолчанию (strict, без допуска)
 func Validate(log *slog.Logger, o *models.Order) error {
-	var errs []string
-	validateOrder(&errs, o)
-	validateDelivery(&errs, &o.Delivery)
-	validatePayment(&errs, &o.Payment, o)
-	validateItems(&errs, o)
+	return defaultValidator.Validate(log, o)
+}
+
+// Validate - проверяет поля заказа по профилю правил, выбранному по entry/delivery_service,
+// и финансовые инварианты. Ошибка имеет тип ValidationErrors
+func (v *Validator) Validate(log *slog.Logger, o *models.Order) error {
+	var errs ValidationErrors
+	p := v.rules.Load().profileFor(o)
+	p.validate(&errs, o)
+
+	if violations := validateFinancials(o, v.opts.Tolerance); len(violations) > 0 {
+		if v.opts.Mode == ModeLenient {
+			if log != nil {
+				log.Warn("order financial inconsistency",
+					slog.String("order_uid", o.OrderUID),
+					slog.Any("violations", violations),
+				)
+			}
+		} else {
+			errs = append(errs, violations...)
+		}
+	}
 
 	if len(errs) > 0 {
 		if log != nil {
 			log.Warn("order validation failed",
 				slog.String("order_uid", o.OrderUID),
+				slog.String("profile", p.name),
 				slog.Any("errors", errs),
 			)
 		}
-		return fmt.Errorf("%w: %s", ErrBadMessage, strings.Join(errs, "; "))
+		return errs
 	}
 	return nil
 }
 
-func validateOrder(errs *[]string, o *models.Order) {
-	if strings.TrimSpace(o.OrderUID) == "" {
-		*errs = append(*errs, "order_uid is required")
+// ValidateStatusEvent - проверяет событие смены статуса заказа
+func ValidateStatusEvent(e *models.StatusEvent) error {
+	var errs ValidationErrors
+	if strings.TrimSpace(e.OrderUID) == "" {
+		errs.add("order_uid", CodeRequired, "is required")
 	}
-	if strings.TrimSpace(o.TrackNumber) == "" {
-		*errs = append(*errs, "track_number is required")
+	if e.Status == "" {
+		errs.add("status", CodeRequired, "is required")
+	} else if !e.Status.Valid() {
+		errs.add("status", CodeInvalidValue, "is invalid")
 	}
-	if strings.TrimSpace(o.Entry) == "" {
-		*errs = append(*errs, "entry is required")
-	}
-	if strings.TrimSpace(o.DeliveryService) == "" {
-		*errs = append(*errs, "delivery_service is required")
-	}
-	if o.DateCreated.IsZero() {
-		*errs = append(*errs, "date_created is required")
-	}
-}
 
-func validateDelivery(errs *[]string, d *models.Delivery) {
-	if strings.TrimSpace(d.Name) == "" {
-		*errs = append(*errs, "delivery.name is required")
-	}
-	if strings.TrimSpace(d.Phone) == "" {
-		*errs = append(*errs, "delivery.phone is required")
-	}
-	if strings.TrimSpace(d.Zip) == "" {
-		*errs = append(*errs, "delivery.zip is required")
-	}
-	if strings.TrimSpace(d.City) == "" {
-		*errs = append(*errs, "delivery.city is required")
-	}
-	if strings.TrimSpace(d.Address) == "" {
-		*errs = append(*errs, "delivery.address is required")
-	}
-	if strings.TrimSpace(d.Region) == "" {
-		*errs = append(*errs, "delivery.region is required")
-	}
-	if strings.TrimSpace(d.Email) == "" {
-		*errs = append(*errs, "delivery.email is required")
-	} else if !isValidEmail(d.Email) {
-		*errs = append(*errs, "delivery.email has invalid format")
+	if len(errs) > 0 {
+		return errs
 	}
+	return nil
 }
 
-func validatePayment(errs *[]string, p *models.Payment, o *models.Order) {
-	if strings.TrimSpace(p.Transaction) == "" {
-		*errs = append(*errs, "payment.transaction is required")
-	}
-	if p.Transaction != o.OrderUID {
-		*errs = append(*errs, "payment.transaction must equal order_uid")
-	}
-	if strings.TrimSpace(p.Currency) == "" {
-		*errs = append(*errs, "payment.currency is required")
-	}
-	if strings.TrimSpace(p.Provider) == "" {
-		*errs = append(*errs, "payment.provider is required")
-	}
-	if p.PaymentDT <= 0 {
-		*errs = append(*errs, "payment.payment_dt must be positive")
-	}
-
-	if p.Amount < 0 {
-		*errs = append(*errs, "payment.amount must be >= 0")
-	}
-	if p.DeliveryCost < 0 {
-		*errs = append(*errs, "payment.delivery_cost must be >= 0")
-	}
-	if p.GoodsTotal < 0 {
-		*errs = append(*errs, "payment.goods_total must be >= 0")
-	}
-	if p.CustomFee < 0 {
-		*errs = append(*errs, "payment.custom_fee must be >= 0")
-	}
-}
+// validateFinancials - проверяет, что суммы заказа сходятся между собой с точностью до tolerance:
+// стоимость позиции - цена за вычетом скидки в процентах (с округлением до целого),
+// goods_total - сумма стоимостей позиций, amount - goods_total + delivery_cost + custom_fee
+func validateFinancials(o *models.Order, tolerance int) ValidationErrors {
+	var violations ValidationErrors
 
-func validateItems(errs *[]string, o *models.Order) {
+	goodsTotal := 0
 	for i, it := range o.Items {
-		pfx := fmt.Sprintf("items[%d]", i)
+		goodsTotal += it.TotalPrice
 
-		if it.ChrtID <= 0 {
-			*errs = append(*errs, pfx+": chrt_id must be > 0")
-		}
-		if strings.TrimSpace(it.TrackNumber) == "" {
-			*errs = append(*errs, pfx+": track_number is required")
-		}
-		if it.Price < 0 {
-			*errs = append(*errs, pfx+": price must be >= 0")
-		}
-		if strings.TrimSpace(it.Rid) == "" {
-			*errs = append(*errs, pfx+": rid is required")
-		}
-		if strings.TrimSpace(it.Name) == "" {
-			*errs = append(*errs, pfx+": name is required")
-		}
-		if it.Sale < 0 {
-			*errs = append(*errs, pfx+": sale must be >= 0")
+		if it.Sale > 100 {
+			violations.add(fmt.Sprintf("items[%d].sale", i), CodeOutOfRange, "must be <= 100")
+			continue
 		}
-		if it.TotalPrice < 0 {
-			*errs = append(*errs, pfx+": total_price must be >= 0")
-		}
-		if it.NmID <= 0 {
-			*errs = append(*errs, pfx+": nm_id must be > 0")
+		//точная стоимость может быть дробной, поэтому допускается округление в любую сторону
+		exact := float64(it.Price) * float64(100-it.Sale) / 100
+		if math.Abs(float64(it.TotalPrice)-exact) >= float64(1+tolerance) {
+			violations.add(fmt.Sprintf("items[%d].total_price", i), CodeInconsistentTotal, fmt.Sprintf(
+				"must equal price minus sale percent (got %d, want %d)", it.TotalPrice, int(exact)))
 		}
+	}
 
-		if it.TrackNumber != o.TrackNumber {
-			*errs = append(*errs, pfx+": track_number must equal order.track_number")
-		}
+	p := &o.Payment
+	if len(o.Items) > 0 && abs(p.GoodsTotal-goodsTotal) > tolerance {
+		violations.add("payment.goods_total", CodeInconsistentTotal, fmt.Sprintf(
+			"must equal sum of items[].total_price (got %d, want %d)", p.GoodsTotal, goodsTotal))
 	}
+
+	wantAmount := p.GoodsTotal + p.DeliveryCost + p.CustomFee
+	if abs(p.Amount-wantAmount) > tolerance {
+		violations.add("payment.amount", CodeInconsistentTotal, fmt.Sprintf(
+			"must equal goods_total + delivery_cost + custom_fee (got %d, want %d)", p.Amount, wantAmount))
+	}
+
+	return violations
 }
 
-func isValidEmail(email string) bool {
-	_, err := mail.ParseAddress(email)
-	return err == nil
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
 }
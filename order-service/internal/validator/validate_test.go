@@ -60,7 +60,7 @@ func createValidOrder() *models.Order {
 		DeliveryService:   "meest",
 		Shardkey:          "9",
 		SmID:              99,
-		DateCreated:       time.Now(),
+		DateCreated:       time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
 		OofShard:          "1",
 	}
 }
@@ -136,6 +136,13 @@ func TestValidate_OrderFields(t *testing.T) {
 			},
 			wantError: "date_created is required",
 		},
+		{
+			name: "unknown status",
+			modifyOrder: func(o *models.Order) {
+				o.Status = "lost"
+			},
+			wantError: "status is invalid",
+		},
 	}
 
 	for _, tt := range tests {
@@ -344,70 +351,70 @@ func TestValidate_ItemsFields(t *testing.T) {
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].ChrtID = 0
 			},
-			wantError: "items[0]: chrt_id must be > 0",
+			wantError: "items[0].chrt_id must be > 0",
 		},
 		{
 			name: "negative chrt_id",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].ChrtID = -1
 			},
-			wantError: "items[0]: chrt_id must be > 0",
+			wantError: "items[0].chrt_id must be > 0",
 		},
 		{
 			name: "empty item track_number",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].TrackNumber = ""
 			},
-			wantError: "items[0]: track_number is required",
+			wantError: "items[0].track_number is required",
 		},
 		{
 			name: "item track_number not equal order track_number",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].TrackNumber = "DIFFERENT_TRACK"
 			},
-			wantError: "items[0]: track_number must equal order.track_number",
+			wantError: "items[0].track_number must equal order.track_number",
 		},
 		{
 			name: "negative price",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].Price = -100
 			},
-			wantError: "items[0]: price must be >= 0",
+			wantError: "items[0].price must be >= 0",
 		},
 		{
 			name: "empty rid",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].Rid = ""
 			},
-			wantError: "items[0]: rid is required",
+			wantError: "items[0].rid is required",
 		},
 		{
 			name: "empty item name",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].Name = ""
 			},
-			wantError: "items[0]: name is required",
+			wantError: "items[0].name is required",
 		},
 		{
 			name: "negative sale",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].Sale = -10
 			},
-			wantError: "items[0]: sale must be >= 0",
+			wantError: "items[0].sale must be >= 0",
 		},
 		{
 			name: "negative total_price",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].TotalPrice = -50
 			},
-			wantError: "items[0]: total_price must be >= 0",
+			wantError: "items[0].total_price must be >= 0",
 		},
 		{
 			name: "zero nm_id",
 			modifyOrder: func(o *models.Order) {
 				o.Items[0].NmID = 0
 			},
-			wantError: "items[0]: nm_id must be > 0",
+			wantError: "items[0].nm_id must be > 0",
 		},
 	}
 
@@ -442,6 +449,228 @@ func TestValidate_MultipleItems(t *testing.T) {
 
 	err := validator.Validate(nil, order)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "items[1]: chrt_id must be > 0")
-	assert.Contains(t, err.Error(), "items[1]: track_number must equal order.track_number")
+	assert.Contains(t, err.Error(), "items[1].chrt_id must be > 0")
+	assert.Contains(t, err.Error(), "items[1].track_number must equal order.track_number")
+}
+
+func TestValidate_Financials(t *testing.T) {
+	tests := []struct {
+		name        string
+		modifyOrder func(*models.Order)
+		wantError   string
+	}{
+		{
+			name: "goods_total not equal sum of items",
+			modifyOrder: func(o *models.Order) {
+				o.Payment.GoodsTotal = 300
+				o.Payment.Amount = 1800
+			},
+			wantError: "payment.goods_total must equal sum of items[].total_price (got 300, want 317)",
+		},
+		{
+			name: "amount not equal goods_total + delivery_cost + custom_fee",
+			modifyOrder: func(o *models.Order) {
+				o.Payment.CustomFee = 10
+			},
+			wantError: "payment.amount must equal goods_total + delivery_cost + custom_fee (got 1817, want 1827)",
+		},
+		{
+			name: "total_price not equal price minus sale",
+			modifyOrder: func(o *models.Order) {
+				o.Items[0].TotalPrice = 453
+			},
+			wantError: "items[0].total_price must equal price minus sale percent (got 453, want 317)",
+		},
+		{
+			name: "sale greater than 100",
+			modifyOrder: func(o *models.Order) {
+				o.Items[0].Sale = 120
+			},
+			wantError: "items[0].sale must be <= 100",
+		},
+		{
+			name: "second item total_price mismatch",
+			modifyOrder: func(o *models.Order) {
+				o.Items = append(o.Items, models.Item{
+					ChrtID:      1,
+					TrackNumber: "WBILMTESTTRACK",
+					Price:       100,
+					Rid:         "rid2",
+					Name:        "Item 2",
+					Sale:        50,
+					TotalPrice:  60,
+					NmID:        123456,
+				})
+				o.Payment.GoodsTotal = 377
+				o.Payment.Amount = 1877
+			},
+			wantError: "items[1].total_price must equal price minus sale percent (got 60, want 50)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := createValidOrder()
+			tt.modifyOrder(order)
+
+			err := validator.Validate(nil, order)
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, validator.ErrBadMessage))
+			assert.Contains(t, err.Error(), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_FinancialsRounding(t *testing.T) {
+	order := createValidOrder()
+	// 453 * 0.7 = 317.1 - допустимо округление в любую сторону
+	order.Items[0].TotalPrice = 318
+	order.Payment.GoodsTotal = 318
+	order.Payment.Amount = 1818
+
+	assert.NoError(t, validator.Validate(nil, order))
+}
+
+func TestValidate_FinancialsTolerance(t *testing.T) {
+	order := createValidOrder()
+	order.Payment.Amount = 1819
+
+	assert.Error(t, validator.Validate(nil, order))
+
+	v := validator.New(validator.Options{Mode: validator.ModeStrict, Tolerance: 2})
+	assert.NoError(t, v.Validate(nil, order))
+
+	order.Payment.Amount = 1820
+	err := v.Validate(nil, order)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "payment.amount must equal")
+}
+
+func TestValidate_FinancialsLenient(t *testing.T) {
+	order := createValidOrder()
+	order.Payment.GoodsTotal = 300
+	order.Items[0].TotalPrice = 453
+
+	v := validator.New(validator.Options{Mode: validator.ModeLenient})
+	assert.NoError(t, v.Validate(createTestLogger(), order))
+
+	// остальные проверки в lenient-режиме не ослабляются
+	order.Payment.Currency = ""
+	err := v.Validate(nil, order)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "payment.currency is required")
+	assert.NotContains(t, err.Error(), "goods_total must equal")
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    validator.Mode
+		wantErr bool
+	}{
+		{in: "", want: validator.ModeStrict},
+		{in: "strict", want: validator.ModeStrict},
+		{in: "lenient", want: validator.ModeLenient},
+		{in: "loose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := validator.ParseMode(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateStatusEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     models.StatusEvent
+		wantError string
+	}{
+		{
+			name:  "valid",
+			event: models.StatusEvent{OrderUID: "uid", Status: models.StatusPaid},
+		},
+		{
+			name:      "empty order_uid",
+			event:     models.StatusEvent{Status: models.StatusPaid},
+			wantError: "order_uid is required",
+		},
+		{
+			name:      "empty status",
+			event:     models.StatusEvent{OrderUID: "uid"},
+			wantError: "status is required",
+		},
+		{
+			name:      "unknown status",
+			event:     models.StatusEvent{OrderUID: "uid", Status: "lost"},
+			wantError: "status is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateStatusEvent(&tt.event)
+			if tt.wantError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, validator.ErrBadMessage))
+			assert.Contains(t, err.Error(), tt.wantError)
+		})
+	}
+}
+
+func TestValidate_StructuredErrors(t *testing.T) {
+	order := createValidOrder()
+	order.Delivery.Email = "not-an-email"
+	order.Items[0].NmID = 0
+
+	err := validator.Validate(nil, order)
+
+	var verrs validator.ValidationErrors
+	assert.True(t, errors.As(err, &verrs))
+	assert.True(t, errors.Is(err, validator.ErrBadMessage))
+	assert.Equal(t, validator.ValidationErrors{
+		{Field: "delivery.email", Code: validator.CodeInvalidFormat, Message: "has invalid format"},
+		{Field: "items[0].nm_id", Code: validator.CodeMustBePositive, Message: "must be > 0"},
+	}, verrs)
+	assert.Equal(t,
+		`[{"field":"delivery.email","code":"invalid_format","message":"has invalid format"},`+
+			`{"field":"items[0].nm_id","code":"must_be_positive","message":"must be > 0"}]`,
+		verrs.JSON())
+	assert.Equal(t, "bad_message: delivery.email has invalid format; items[0].nm_id must be > 0", err.Error())
+}
+
+func TestValidate_StructuredErrorsFinancials(t *testing.T) {
+	order := createValidOrder()
+	order.Payment.Amount = 1800
+
+	err := validator.Validate(nil, order)
+
+	var verrs validator.ValidationErrors
+	assert.True(t, errors.As(err, &verrs))
+	assert.Equal(t, validator.ValidationErrors{{
+		Field:   "payment.amount",
+		Code:    validator.CodeInconsistentTotal,
+		Message: "must equal goods_total + delivery_cost + custom_fee (got 1800, want 1817)",
+	}}, verrs)
+}
+
+func TestValidateStatusEvent_StructuredErrors(t *testing.T) {
+	err := validator.ValidateStatusEvent(&models.StatusEvent{Status: "lost"})
+
+	var verrs validator.ValidationErrors
+	assert.True(t, errors.As(err, &verrs))
+	assert.True(t, errors.Is(err, validator.ErrBadMessage))
+	assert.Equal(t, validator.ValidationErrors{
+		{Field: "order_uid", Code: validator.CodeRequired, Message: "is required"},
+		{Field: "status", Code: validator.CodeInvalidValue, Message: "is invalid"},
+	}, verrs)
 }
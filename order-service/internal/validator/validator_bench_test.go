@@ -26,11 +26,11 @@ func benchValidOrder() *models.Order {
 			Transaction:  uid,
 			Currency:     "RUB",
 			Provider:     "wbpay",
-			Amount:       1000,
-			PaymentDT:    1637907727,
+			Amount:       1315,
+			PaymentDT:    1704067200,
 			Bank:         "Sberbank",
 			DeliveryCost: 200,
-			GoodsTotal:   800,
+			GoodsTotal:   1115,
 		},
 		Items: []models.Item{
 			{
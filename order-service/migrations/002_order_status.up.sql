@@ -0,0 +1,18 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'created';
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id          BIGSERIAL PRIMARY KEY,
+    order_id    VARCHAR(255) NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status   VARCHAR(20)  NOT NULL,
+    changed_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    reason      VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id, id);
+
+-- у заказов, сохранённых до появления статусов, история начинается с created
+INSERT INTO order_status_history (order_id, from_status, to_status, changed_at)
+SELECT o.order_uid, NULL, o.status, COALESCE(o.date_created, NOW())
+FROM orders o
+WHERE NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_id = o.order_uid);
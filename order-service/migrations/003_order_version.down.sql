@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS version;
+ALTER TABLE orders DROP COLUMN IF EXISTS content_hash;
\ No newline at end of file
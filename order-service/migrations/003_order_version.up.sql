@@ -0,0 +1,3 @@
+-- хеш содержимого заказа: повтор того же сообщения не меняет бд, изменённый заказ обрабатывается по ORDER_CONFLICT_POLICY
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS content_hash CHAR(64);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,10 @@
+-- индексы под поиск заказов GET /orders: keyset-пагинация по (date_created, order_uid) и фильтры
+CREATE INDEX IF NOT EXISTS idx_orders_date_created ON orders(date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id, date_created DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders(track_number);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service ON orders(delivery_service, date_created DESC);
+CREATE INDEX IF NOT EXISTS idx_delivery_phone ON delivery(phone);
+CREATE INDEX IF NOT EXISTS idx_delivery_email ON delivery(email);
+CREATE INDEX IF NOT EXISTS idx_payments_transaction ON payments(transaction);
+CREATE INDEX IF NOT EXISTS idx_items_nm_id ON items(nm_id);
+CREATE INDEX IF NOT EXISTS idx_items_brand ON items(brand);
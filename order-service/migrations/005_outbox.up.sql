@@ -0,0 +1,14 @@
+-- transactional outbox: события пишутся в той же транзакции, что и заказ, и отправляются в Kafka релеем.
+-- event_id - ключ дедупликации для потребителей, sent_at IS NULL - событие ещё не отправлено
+CREATE TABLE IF NOT EXISTS outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    event_id     VARCHAR(300) NOT NULL UNIQUE,
+    event_type   VARCHAR(50)  NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    payload      JSONB        NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    sent_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unsent ON outbox(id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON outbox(sent_at) WHERE sent_at IS NOT NULL;
@@ -93,11 +93,11 @@ produce "ok_multi_items_1" <<'JSON'
     "request_id": "req123",
     "currency": "RUB",
     "provider": "wbpay",
-    "amount": 5000,
-    "payment_dt": 1637907727,
+    "amount": 4950,
+    "payment_dt": 1701424800,
     "bank": "sberbank",
     "delivery_cost": 300,
-    "goods_total": 4700,
+    "goods_total": 4650,
     "custom_fee": 0
   },
   "items": [